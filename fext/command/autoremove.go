@@ -0,0 +1,90 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/pkg"
+	"github.com/fextpkg/cli/fext/ui"
+)
+
+type Autoremove struct {
+	// Flags
+	dryRun bool // Only show the packages that would be removed
+	yes    bool // Remove the packages without asking for confirmation
+}
+
+// DetectFlags analyzes the passed flags and fills in the variables associated
+// with them.
+//
+// Returns ferror.HelpFlag if you need to print the docstring about this command.
+// Returns ferror.UnknownFlag if passed the unknown flag.
+func (cmd *Autoremove) DetectFlags() error {
+	for _, f := range config.Flags {
+		switch f {
+		case "h", "help":
+			return ferror.HelpFlag
+		case "n", "dry-run":
+			cmd.dryRun = true
+		case "y", "yes":
+			cmd.yes = true
+		default:
+			return &ferror.UnknownFlag{Flag: f}
+		}
+	}
+
+	return nil
+}
+
+// Execute removes the packages installed as dependencies that no requested
+// package needs anymore. The packages are listed first, and removed after the
+// confirmation unless yes is enabled. If dryRun is enabled, the packages are
+// only listed.
+// Nothing is removed if the metadata of any package can't be processed, since
+// the packages it depends on can't be told apart from the orphans.
+func (cmd *Autoremove) Execute() {
+	g, err := pkg.LoadGraph()
+	if err != nil {
+		ui.Fatal("Unable to load packages:", err.Error())
+	} else if len(g.GetSkipped()) > 0 {
+		warnSkipped(g)
+		ui.Fatal("Unable to load packages: the dependencies of the skipped packages are unknown")
+	}
+
+	orphans := g.CollectOrphans()
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned packages found")
+		return
+	}
+
+	fmt.Println("Would remove:")
+	for _, name := range orphans {
+		p := g.Get(name)
+		fmt.Printf("%s (%s)\n", p.Name, p.Version)
+	}
+	if cmd.dryRun {
+		return
+	} else if !cmd.yes && !ui.Confirm("Proceed?") {
+		fmt.Println("Nothing was removed")
+		return
+	}
+
+	for _, name := range orphans {
+		p := g.Get(name)
+		if err = p.Uninstall(); err != nil {
+			ui.PrintfError("Uninstall %s: %v\n", p.Name, err)
+		} else {
+			ui.PrintfMinus("%s (%s)\n", p.Name, p.Version)
+		}
+	}
+}
+
+// InitAutoremove initializes "autoremove" command structure with the default
+// parameters.
+func InitAutoremove() *Autoremove {
+	return &Autoremove{
+		dryRun: false,
+		yes:    false,
+	}
+}
@@ -1,8 +1,13 @@
 package command
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/expression"
 	"github.com/fextpkg/cli/fext/io"
 	"github.com/fextpkg/cli/fext/pkg"
@@ -92,11 +97,59 @@ func (cmd *CheckPackageHealth) scanMismatchingDependencies(p *pkg.Package) ([]st
 	return deps, nil
 }
 
+// scanPermissions looks through the files listed in the package RECORD for
+// the ones that have more permissions than they should, e.g. the files
+// writable by others, or the executable modules. Only the files installed
+// executable are expected to be executable, see pkg.Package.GetExecutableFiles.
+// Missing permissions are not reported. The packages
+// without the RECORD file are not checked, since their files can't be told
+// apart from the files of other packages sharing the same directories.
+// Returns a list of files with their actual and expected permissions, or error
+// if the package files can't be read.
+func (cmd *CheckPackageHealth) scanPermissions(p *pkg.Package) ([]string, error) {
+	var files []string
+
+	paths, err := p.GetRecordFiles()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	executables, err := p.GetExecutableFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Some listed files may not exist, e.g. the ones removed by
+			// the user
+			continue
+		} else if err != nil {
+			return nil, err
+		} else if info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		expected := io.GetExpectedMode(info, executables[path])
+		if actual := info.Mode().Perm(); actual&^expected != 0 {
+			rel, _ := filepath.Rel(config.PythonLibPath, path)
+			files = append(files, fmt.Sprintf("%s (%v, expected %v)", rel, actual, expected))
+		}
+	}
+
+	return files, nil
+}
+
 // checkPackageDependencies checks a package for installation errors. If the
 // package has incompatible versions of dependencies, or they are missing
-// altogether, an error will be displayed. If the package fails to load,
+// altogether, or its files have unexpected permissions, an error will be
+// displayed. If the package fails to load,
 // an error will also be displayed.
-// Returns the total number of missing and incompatible dependencies.
+// Returns the total number of missing and incompatible dependencies, and files
+// with unexpected permissions.
 func (cmd *CheckPackageHealth) checkPackageDependencies(metaDir string) (int, error) {
 	p, err := pkg.LoadFromMetaDir(metaDir)
 	if err != nil {
@@ -125,7 +178,18 @@ func (cmd *CheckPackageHealth) checkPackageDependencies(metaDir string) (int, er
 		)
 	}
 
-	return len(missingDeps) + len(mismatchingDeps), nil
+	wrongPermissions, err := cmd.scanPermissions(p)
+	if err != nil {
+		return 1, err
+	} else if len(wrongPermissions) > 0 {
+		ui.PrintfError(
+			"check %s: unexpected permissions: %s\n",
+			p.Name,
+			strings.Join(wrongPermissions, ", "),
+		)
+	}
+
+	return len(missingDeps) + len(mismatchingDeps) + len(wrongPermissions), nil
 }
 
 // DetectFlags does nothing and is a stub to maintain a single interface of
@@ -136,9 +200,9 @@ func (cmd *CheckPackageHealth) DetectFlags() error {
 
 // Execute has iterates through all packages installed in the system and check
 // if everything is fine with them. If any issues are found with a package
-// (incompatibility with dependencies, missing packages, or failed to load),
-// an error message will be displayed. Otherwise, if everything is fine,
-// an "ok" message will be displayed.
+// (incompatibility with dependencies, missing packages, unexpected file
+// permissions, or failed to load), an error message will be displayed.
+// Otherwise, if everything is fine, an "ok" message will be displayed.
 func (cmd *CheckPackageHealth) Execute() {
 	var brokenPackages int
 	var err error
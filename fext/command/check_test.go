@@ -0,0 +1,52 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+func TestCheckPackageHealth_scanPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the files have no executable bit on Windows")
+	}
+
+	libPath, scriptsPath := config.PythonLibPath, config.ScriptsPath
+	t.Cleanup(func() { config.PythonLibPath, config.ScriptsPath = libPath, scriptsPath })
+	root := t.TempDir()
+	config.PythonLibPath = filepath.Join(root, "lib")
+	config.ScriptsPath = filepath.Join(root, "bin")
+
+	files := map[string]string{
+		"lib/tool/__init__.py":                              "",
+		"lib/tool/module.py":                                "",
+		"lib/tool/native.so":                                "",
+		"bin/tool":                                          "",
+		"lib/tool-1.0.dist-info/METADATA":                   "Name: tool\nVersion: 1.0\n",
+		"lib/tool-1.0.dist-info/RECORD":                     "tool/__init__.py,,\ntool/module.py,,\ntool/native.so,,\n../bin/tool,,\n",
+		"lib/tool-1.0.dist-info/" + pkg.ExecutablesFileName: "tool/native.so\n../bin/tool\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	for _, name := range []string{"lib/tool/module.py", "lib/tool/native.so", "bin/tool"} {
+		assert.Nil(t, os.Chmod(filepath.Join(root, filepath.FromSlash(name)), 0755))
+	}
+
+	p, err := pkg.LoadFromMetaDir("tool-1.0.dist-info")
+	assert.Nil(t, err)
+	wrong, err := InitCheckPackageHealth().scanPermissions(p)
+	assert.Nil(t, err)
+	// Only the module that was not installed executable is reported
+	assert.Len(t, wrong, 1)
+	assert.True(t, strings.HasPrefix(wrong[0], filepath.Join("tool", "module.py")), wrong)
+}
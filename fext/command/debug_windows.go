@@ -22,9 +22,12 @@ func (cmd *Debug) DetectFlags() error {
 // Execute prints debug info.
 func (cmd *Debug) Execute() {
 	fmt.Printf(
-		"Fext (%s)\n\nLinked to: %s\nPython version: %s\nSystem platform: %s (tag: %s)\nChange mode: %v\nOS: %s, arch: %s\n",
+		"Fext (%s)\n\nLinked to: %s\nScripts: %s\nDownloads: %s\nConfig: %s\nPython version: %s\nSystem platform: %s (tag: %s)\nChange mode: %v\nOS: %s, arch: %s\n",
 		ui.BoldString(config.Version),
 		ui.BoldString(config.PythonLibPath),
+		ui.BoldString(config.ScriptsPath),
+		ui.BoldString(config.DownloadPath),
+		ui.BoldString(config.ConfigFilePath),
 		ui.BoldString(config.PythonVersion),
 		ui.BoldString(config.MarkerPlatform),
 		ui.BoldString(config.PlatformTag),
@@ -32,6 +35,10 @@ func (cmd *Debug) Execute() {
 		ui.BoldString(runtime.GOOS),
 		ui.BoldString(runtime.GOARCH),
 	)
+
+	if !config.IsInPath(config.ScriptsPath) {
+		ui.PrintfWarning("The scripts directory is not in PATH, installed commands can't be run by name\n")
+	}
 }
 
 // InitDebug initializes the "debug" command structure.
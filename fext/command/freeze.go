@@ -2,11 +2,13 @@ package command
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/ferror"
 	"github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/io/installer"
 	"github.com/fextpkg/cli/fext/pkg"
 	"github.com/fextpkg/cli/fext/ui"
 )
@@ -17,6 +19,9 @@ type Freeze struct {
 
 	// Directory names with packages metadata for scanning and output
 	metaDirectories []string
+
+	// Options of the package lookup for the lock file
+	options *installer.Options
 }
 
 // getPrintFunc selects the functions responsible for the specified printMode. If
@@ -26,6 +31,8 @@ func (cmd *Freeze) getPrintFunc() (func(), error) {
 		return cmd.printStyleHuman, nil
 	} else if cmd.printMode == "pip" {
 		return cmd.printStylePIP, nil
+	} else if cmd.printMode == "pylock" {
+		return cmd.printStylePyLock, nil
 	} else {
 		return nil, &ferror.UnexpectedMode{Mode: cmd.printMode}
 	}
@@ -67,6 +74,7 @@ func (cmd *Freeze) printStyleHuman() {
 }
 
 // printStylePIP outputs a list of packages in PIP style, but with comments.
+// Packages installed from direct references are printed with their URLs.
 // Packages that failed to load will be commented out.
 func (cmd *Freeze) printStylePIP() {
 	fmt.Printf(
@@ -76,7 +84,14 @@ func (cmd *Freeze) printStylePIP() {
 
 	for _, metaDir := range cmd.metaDirectories {
 		p, err := pkg.LoadFromMetaDir(metaDir)
+		var directURL *pkg.DirectURL
 		if err == nil {
+			directURL, err = p.GetDirectURL()
+		}
+
+		if err == nil && directURL != nil {
+			fmt.Printf("%s @ %s\n", p.Name, directURL.URL)
+		} else if err == nil {
 			fmt.Printf("%s==%s\n", p.Name, p.Version)
 		} else {
 			fmt.Printf("# %s\n", metaDir)
@@ -84,13 +99,37 @@ func (cmd *Freeze) printStylePIP() {
 	}
 }
 
+// printStylePyLock outputs the lock file in the PEP 751 format describing the
+// installed packages, so it can be saved as "pylock.toml". The wheels are
+// looked up in the indexes set by the options. Packages that failed to load
+// are skipped.
+func (cmd *Freeze) printStylePyLock() {
+	var packages []*pkg.Package
+	for _, metaDir := range cmd.metaDirectories {
+		p, err := pkg.LoadFromMetaDir(metaDir)
+		if err == nil {
+			packages = append(packages, p)
+		}
+	}
+
+	if err := installer.NewInstaller(cmd.options).WritePyLock(os.Stdout, packages); err != nil {
+		ui.Fatal("Unable to write lock file:", err.Error())
+	}
+}
+
 // DetectFlags analyzes the passed flags and fills in the variables associated
 // with them.
 //
 // Returns ferror.HelpFlag if you need to print the docstring about this command.
 // Returns ferror.UnknownFlag if passed the unknown flag.
-// Returns ferror.MissingOptionValue if the correct but empty option is passed.
+// Returns ferror.MissingOptionValue if the correct but empty option is passed,
+// or ferror.InvalidIndexURL if the index URL is invalid.
 func (cmd *Freeze) DetectFlags() error {
+	// The configuration file sets the defaults, which the flags override
+	if err := cmd.options.ReadConfigFile(config.ConfigFilePath); err != nil {
+		return err
+	}
+
 	for _, f := range config.Flags {
 		if f == "h" || f == "help" { // Help flag
 			return ferror.HelpFlag
@@ -104,7 +143,9 @@ func (cmd *Freeze) DetectFlags() error {
 			}
 		} else if f == "m" || f == "mode" { // Passed "mode" option without value
 			return &ferror.MissingOptionValue{Opt: f}
-		} else { // Unexpected flag
+		} else if ok, err := detectSourceFlag(f, cmd.options); err != nil {
+			return err
+		} else if !ok { // Unexpected flag
 			return &ferror.UnknownFlag{Flag: f}
 		}
 	}
@@ -131,5 +172,6 @@ func (cmd *Freeze) Execute() {
 func InitFreeze() *Freeze {
 	return &Freeze{
 		printMode: "human",
+		options:   installer.DefaultOptions(),
 	}
 }
@@ -1,10 +1,14 @@
 package command
 
 import (
+	"path/filepath"
+	"strings"
+
 	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/ferror"
 	"github.com/fextpkg/cli/fext/io"
 	"github.com/fextpkg/cli/fext/io/installer"
+	"github.com/fextpkg/cli/fext/io/web"
 	"github.com/fextpkg/cli/fext/ui"
 )
 
@@ -13,6 +17,9 @@ type Install struct {
 	// of packages names
 	fileMode bool
 
+	// Install the exact packages pinned in the lock files passed in arguments
+	lockedMode bool
+
 	// List of packages to be installed
 	packages []string
 
@@ -20,24 +27,105 @@ type Install struct {
 	options *installer.Options
 }
 
-// getPackagesFromFiles retrieves the list of packages names from the files
-// passed instead of the package list. This method is used when fileMode is
-// enabled.
-func (cmd *Install) getPackagesFromFiles() ([]string, error) {
+// getPackagesFromFiles retrieves the list of packages names from the
+// requirements files. Lines consisting of a single option are applied to the
+// installation options, only "--require-hashes", "--no-index" and the options
+// of the package source are supported, see applySourceOption. Relative
+// find-links directories are resolved against the directory of the file.
+// Returns ferror.UnknownFlag if another option is found in the file, or
+// ferror.MissingOptionValue if the option value is not passed.
+func getPackagesFromFiles(fileNames []string, options *installer.Options) ([]string, error) {
 	var packages []string
-	for _, fileName := range cmd.packages {
-		p, err := io.ReadLinesWithComments(fileName)
+	for _, fileName := range fileNames {
+		lines, err := io.ReadLinesWithComments(fileName)
 		if err != nil {
 			return nil, err
 		}
-		packages = append(packages, p...)
+
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "-") {
+				packages = append(packages, line)
+				continue
+			}
+
+			opt, value, _ := strings.Cut(strings.TrimLeft(line, "-"), "=")
+			if fields := strings.Fields(opt); len(fields) == 2 {
+				// The value is separated with a space
+				opt, value = fields[0], fields[1]
+			}
+
+			switch opt {
+			case "require-hashes":
+				options.RequireHashes = true
+			case "no-index":
+				options.Offline = true
+			case "f", "find-links", "i", "index-url", "extra-index-url":
+				if value == "" {
+					return nil, &ferror.MissingOptionValue{Opt: opt}
+				} else if (opt == "f" || opt == "find-links") && !filepath.IsAbs(value) {
+					value = filepath.Join(filepath.Dir(fileName), value)
+				}
+				if err = applySourceOption(opt, value, options); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, &ferror.UnknownFlag{Flag: opt}
+			}
+		}
 	}
 
 	return packages, nil
 }
 
-// Installs the list of passed packages. Returns an error if extra packages could
-// not be retrievers (they do not exist or load error).
+// detectSourceFlag applies the flag to the options if it configures where the
+// packages are looked up: "--offline" or an option accepted by
+// applySourceOption in the form "--<name>=<value>". Returns false if the flag
+// is not related to the package source.
+// Returns ferror.MissingOptionValue if the option value is not passed, or
+// ferror.InvalidIndexURL if the index URL is invalid.
+func detectSourceFlag(f string, options *installer.Options) (bool, error) {
+	if f == "offline" {
+		options.Offline = true
+		return true, nil
+	}
+
+	name, value, _ := strings.Cut(f, "=")
+	switch name {
+	case "f", "find-links", "i", "index-url", "extra-index-url":
+		if value == "" { // Passed option without value
+			return false, &ferror.MissingOptionValue{Opt: name}
+		}
+		return true, applySourceOption(name, value, options)
+	default:
+		return false, nil
+	}
+}
+
+// applySourceOption applies the option of the package source to the options:
+// "-f, --find-links <dir>", "-i, --index-url <url>" or
+// "--extra-index-url <url>".
+// Returns ferror.InvalidIndexURL if the index URL is invalid.
+func applySourceOption(name, value string, options *installer.Options) error {
+	switch name {
+	case "f", "find-links":
+		options.FindLinks = append(options.FindLinks, value)
+	case "i", "index-url":
+		if err := web.ValidateIndexURL(value); err != nil {
+			return err
+		}
+		options.IndexURL = value
+	case "extra-index-url":
+		if err := web.ValidateIndexURL(value); err != nil {
+			return err
+		}
+		options.ExtraIndexURLs = append(options.ExtraIndexURLs, value)
+	}
+
+	return nil
+}
+
+// Installs the list of passed packages. Returns an error if the package names
+// are invalid or their dependencies could not be resolved.
 func (cmd *Install) install() error {
 	i := installer.NewInstaller(cmd.options)
 	err := i.InitializePackages(cmd.packages)
@@ -45,8 +133,12 @@ func (cmd *Install) install() error {
 		return err
 	}
 
-	i.Install()
-	return nil
+	return i.Install()
+}
+
+// installLocked installs the packages pinned in the passed lock files.
+func (cmd *Install) installLocked() error {
+	return installer.NewInstaller(cmd.options).InstallLocked(cmd.packages)
 }
 
 // DetectFlags analyzes the passed flags and fills in the variables associated
@@ -54,7 +146,13 @@ func (cmd *Install) install() error {
 //
 // Returns ferror.HelpFlag if you need to print the docstring about this command.
 // Returns ferror.UnknownFlag if passed the unknown flag.
+// Returns ferror.MissingOptionValue if the correct but empty option is passed.
 func (cmd *Install) DetectFlags() error {
+	// The configuration file sets the defaults, which the flags override
+	if err := cmd.options.ReadConfigFile(config.ConfigFilePath); err != nil {
+		return err
+	}
+
 	for _, f := range config.Flags {
 		switch f {
 		case "h", "help":
@@ -65,8 +163,16 @@ func (cmd *Install) DetectFlags() error {
 			cmd.options.QuietMode = true
 		case "r", "requirements":
 			cmd.fileMode = true
+		case "require-hashes":
+			cmd.options.RequireHashes = true
+		case "locked":
+			cmd.lockedMode = true
 		default:
-			return &ferror.UnknownFlag{Flag: f}
+			if ok, err := detectSourceFlag(f, cmd.options); err != nil {
+				return err
+			} else if !ok {
+				return &ferror.UnknownFlag{Flag: f}
+			}
 		}
 	}
 
@@ -74,11 +180,19 @@ func (cmd *Install) DetectFlags() error {
 }
 
 // Execute downloads and installs the passed packages using the flags set.
-// Additionally, scans files if fileMode is enabled.
+// Additionally, scans files if fileMode is enabled. If lockedMode is enabled,
+// the packages are installed from the lock files instead.
 func (cmd *Install) Execute() {
 	var err error
+	if cmd.lockedMode {
+		if err = cmd.installLocked(); err != nil {
+			ui.Fatal("Unable to install:", err.Error())
+		}
+		return
+	}
+
 	if cmd.fileMode {
-		cmd.packages, err = cmd.getPackagesFromFiles()
+		cmd.packages, err = getPackagesFromFiles(cmd.packages, cmd.options)
 		if err != nil {
 			ui.Fatal("Failed to read file:", err.Error())
 		}
@@ -90,13 +204,14 @@ func (cmd *Install) Execute() {
 }
 
 // InitInstall initializes "install" command structure with the default
-// parameters. Takes as an argument a list of packages names, or filenames that
-// includes package names, to be installed. Each package name can contain either
-// operators or extra names.
+// parameters. Takes as an argument a list of packages names, filenames that
+// includes package names, or lock files, to be installed. Each package name can
+// contain either operators or extra names.
 func InitInstall(packages []string) *Install {
 	return &Install{
-		fileMode: false,
-		packages: packages,
-		options:  installer.DefaultOptions(),
+		fileMode:   false,
+		lockedMode: false,
+		packages:   packages,
+		options:    installer.DefaultOptions(),
 	}
 }
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/installer"
+	"github.com/fextpkg/cli/fext/ui"
+)
+
+type Lock struct {
+	// Load packages names from the list of files passed in arguments instead
+	// of packages names
+	fileMode bool
+
+	// Path to the lock file to be written
+	output string
+
+	// List of top-level packages to be locked
+	packages []string
+
+	// Resolution options. Are filled in based on the passed flags
+	options *installer.Options
+}
+
+// lock resolves the passed packages and writes the lock file. Returns an error
+// if the package names are invalid or their dependencies could not be resolved.
+func (cmd *Lock) lock() error {
+	i := installer.NewInstaller(cmd.options)
+	err := i.InitializePackages(cmd.packages)
+	if err != nil {
+		return err
+	}
+
+	return i.Lock(cmd.output)
+}
+
+// DetectFlags analyzes the passed flags and fills in the variables associated
+// with them.
+//
+// Returns ferror.HelpFlag if you need to print the docstring about this command.
+// Returns ferror.UnknownFlag if passed the unknown flag.
+// Returns ferror.MissingOptionValue if the correct but empty option is passed.
+func (cmd *Lock) DetectFlags() error {
+	// The configuration file sets the defaults, which the flags override
+	if err := cmd.options.ReadConfigFile(config.ConfigFilePath); err != nil {
+		return err
+	}
+
+	for _, f := range config.Flags {
+		if f == "h" || f == "help" {
+			return ferror.HelpFlag
+		} else if f == "r" || f == "requirements" {
+			cmd.fileMode = true
+		} else if strings.HasPrefix(f, "o=") || strings.HasPrefix(f, "output=") {
+			cmd.output = strings.SplitN(f, "=", 2)[1]
+			if cmd.output == "" { // Empty value
+				return &ferror.MissingOptionValue{Opt: f[:len(f)-1]}
+			}
+		} else if f == "o" || f == "output" { // Passed "output" option without value
+			return &ferror.MissingOptionValue{Opt: f}
+		} else if ok, err := detectSourceFlag(f, cmd.options); err != nil {
+			return err
+		} else if !ok {
+			return &ferror.UnknownFlag{Flag: f}
+		}
+	}
+
+	return nil
+}
+
+// Execute resolves the passed packages and writes the exact versions and
+// distribution files to the lock file. Additionally, scans files if fileMode
+// is enabled.
+func (cmd *Lock) Execute() {
+	var err error
+	if cmd.fileMode {
+		cmd.packages, err = getPackagesFromFiles(cmd.packages, cmd.options)
+		if err != nil {
+			ui.Fatal("Failed to read file:", err.Error())
+		}
+	}
+
+	if err = cmd.lock(); err != nil {
+		ui.Fatal("Unable to lock:", err.Error())
+	}
+
+	ui.PrintlnPlus(cmd.output)
+}
+
+// InitLock initializes "lock" command structure with the default parameters.
+// Takes as an argument a list of packages names, or filenames that includes
+// package names, to be locked.
+func InitLock(packages []string) *Lock {
+	return &Lock{
+		fileMode: false,
+		output:   installer.LockFileName,
+		packages: packages,
+		options:  installer.DefaultOptions(),
+	}
+}
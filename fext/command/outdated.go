@@ -0,0 +1,94 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/io/installer"
+	"github.com/fextpkg/cli/fext/pkg"
+	"github.com/fextpkg/cli/fext/ui"
+)
+
+type Outdated struct {
+	// Flags
+	verbose bool // Print the errors of the index lookup
+
+	// Options of the package lookup
+	options *installer.Options
+}
+
+// DetectFlags analyzes the passed flags and fills in the variables associated
+// with them. The indexes are read from the configuration file.
+//
+// Returns ferror.HelpFlag if you need to print the docstring about this command.
+// Returns ferror.UnknownFlag if passed the unknown flag.
+func (cmd *Outdated) DetectFlags() error {
+	for _, f := range config.Flags {
+		switch f {
+		case "h", "help":
+			return ferror.HelpFlag
+		case "v", "verbose":
+			cmd.verbose = true
+		default:
+			return &ferror.UnknownFlag{Flag: f}
+		}
+	}
+
+	return cmd.options.ReadConfigFile(config.ConfigFilePath)
+}
+
+// Execute looks up the installed packages in the indexes and prints the ones
+// that have newer versions, and the ones whose installed versions have been
+// yanked since. The packages that can't be looked up are counted, and their
+// errors are printed in the verbose mode.
+func (cmd *Outdated) Execute() {
+	metaDirs, err := io.GetMetaDirectories()
+	if err != nil {
+		ui.Fatal("Unable to scan meta directories:", err.Error())
+	}
+
+	var packages []*pkg.Package
+	for _, metaDir := range metaDirs {
+		if p, err := pkg.LoadFromMetaDir(metaDir); err == nil {
+			packages = append(packages, p)
+		} else if cmd.verbose {
+			ui.PrintfWarning("Skipped %s: %v\n", metaDir, err)
+		}
+	}
+
+	var outdated, failed int
+	statuses, errs := installer.NewInstaller(cmd.options).GetRemoteStatuses(packages)
+	for n, status := range statuses {
+		if errs[n] != nil {
+			failed++
+			if cmd.verbose {
+				ui.PrintfWarning("Unable to look up %s: %v\n", packages[n].Name, errs[n])
+			}
+			continue
+		}
+
+		if status.IsOutdated() {
+			outdated++
+			fmt.Printf("%s (%s -> %s)\n", status.Package.Name, status.Package.Version, status.Latest)
+		}
+		printYanked(status)
+	}
+
+	if outdated == 0 {
+		fmt.Println("All packages are up to date")
+	}
+	if failed > 0 && !cmd.verbose {
+		ui.PrintfWarning("Unable to look up %d package(s), use --verbose to see the errors\n", failed)
+	}
+}
+
+// InitOutdated initializes "outdated" command structure with the default
+// parameters.
+func InitOutdated() *Outdated {
+	return &Outdated{
+		verbose: false,
+		options: installer.DefaultOptions(),
+	}
+}
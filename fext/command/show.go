@@ -5,24 +5,54 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/installer"
 	"github.com/fextpkg/cli/fext/pkg"
 	"github.com/fextpkg/cli/fext/ui"
 )
 
 type ShowPackageInfo struct {
+	// Flags
+	checkYanked bool // Check if the installed version has been yanked
+	verbose     bool // Print the errors of the index lookup
+
 	packageNames []string
+
+	// Options of the package lookup, used to check if the installed version
+	// has been yanked
+	options *installer.Options
 }
 
 func InitShowPackageInfo(args []string) *ShowPackageInfo {
 	return &ShowPackageInfo{
+		checkYanked:  false,
+		verbose:      false,
 		packageNames: args,
+		options:      installer.DefaultOptions(),
 	}
 }
 
-// DetectFlags does nothing and is a stub to maintain a single interface of
-// interaction.
+// DetectFlags analyzes the passed flags and fills in the variables associated
+// with them. The indexes are read from the configuration file.
+//
+// Returns ferror.HelpFlag if you need to print the docstring about this command.
+// Returns ferror.UnknownFlag if passed the unknown flag.
 func (cmd *ShowPackageInfo) DetectFlags() error {
-	return nil
+	for _, f := range config.Flags {
+		switch f {
+		case "h", "help":
+			return ferror.HelpFlag
+		case "y", "yanked":
+			cmd.checkYanked = true
+		case "v", "verbose":
+			cmd.verbose = true
+		default:
+			return &ferror.UnknownFlag{Flag: f}
+		}
+	}
+
+	return cmd.options.ReadConfigFile(config.ConfigFilePath)
 }
 
 // Execute prints general information about the first package
@@ -39,6 +69,43 @@ func (cmd *ShowPackageInfo) Execute() {
 	}
 
 	fmt.Println(data)
+	if cmd.checkYanked {
+		cmd.warnYanked(cmd.packageNames[0])
+	}
+}
+
+// warnYanked prints a warning if the installed version of the package has
+// been yanked from the index since. The information is not essential, so the
+// lookup errors are printed in the verbose mode only.
+func (cmd *ShowPackageInfo) warnYanked(pkgName string) {
+	p, err := pkg.Load(pkgName)
+	if err != nil {
+		return
+	}
+
+	status, err := installer.NewInstaller(cmd.options).GetRemoteStatus(p)
+	if err != nil {
+		if cmd.verbose {
+			ui.PrintfWarning("Unable to look up %s: %v\n", p.Name, err)
+		}
+		return
+	}
+
+	printYanked(status)
+}
+
+// printYanked prints a warning if the installed version of the package has
+// been yanked from the index since.
+func printYanked(status *installer.RemoteStatus) {
+	if !status.Yanked {
+		return
+	}
+
+	reason := status.YankedReason
+	if reason == "" {
+		reason = "no reason given"
+	}
+	ui.PrintfWarning("%s %s has been yanked: %s\n", status.Package.Name, status.Package.Version, reason)
 }
 
 // prettifyData loads a package and returns information about it,
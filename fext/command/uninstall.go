@@ -1,6 +1,9 @@
 package command
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/ferror"
 	"github.com/fextpkg/cli/fext/pkg"
@@ -15,28 +18,73 @@ type Uninstall struct {
 	packages []string // List of packages to be uninstalled
 }
 
-// Removes the specified package and all associated files. Also removes its
-// dependencies if collectDependencies is true.
+// uninstall removes the specified package and all associated files.
 // Returns an error if package was failed to load
 func (cmd *Uninstall) uninstall(pkgName string) error {
 	p, err := pkg.Load(pkgName)
 	if err != nil {
 		return err
+	} else if err = p.Uninstall(); err != nil {
+		return err
 	}
 
-	if cmd.collectDependencies {
-		dependencies, err := p.GetDependencies()
-		if err != nil {
-			return err
+	ui.PrintfMinus("%s (%s)\n", p.Name, p.Version)
+	return nil
+}
+
+// warnSkipped prints a warning about each installed package whose metadata
+// can't be processed, so its dependencies are not taken into account.
+func warnSkipped(g *pkg.Graph) {
+	skipped := g.GetSkipped()
+	metaDirs := make([]string, 0, len(skipped))
+	for metaDir := range skipped {
+		metaDirs = append(metaDirs, metaDir)
+	}
+	sort.Strings(metaDirs)
+
+	for _, metaDir := range metaDirs {
+		ui.PrintfWarning("Skipped %s: %v\n", metaDir, skipped[metaDir])
+	}
+}
+
+// uninstallWithDependencies removes the specified packages along with their
+// dependencies that no other installed package needs. The dependencies that
+// are kept are listed with the packages requiring them. The packages whose
+// metadata can't be processed are skipped with a warning.
+// Returns an error if the installed packages can't be listed.
+func (cmd *Uninstall) uninstallWithDependencies() error {
+	g, err := pkg.LoadGraph()
+	if err != nil {
+		return err
+	}
+	warnSkipped(g)
+
+	for _, pkgName := range cmd.packages {
+		if g.Get(pkgName) == nil {
+			ui.PrintfError("Uninstall %s: %v\n", pkgName, ferror.PackageDirectoryMissing)
 		}
-		for _, dep := range dependencies {
-			// Recursion is used here because the uninstallation command is not in use
-			// priority. In the future it will be redone by safe uninstalling (issue #1)
-			cmd.uninstall(dep.PackageName)
+	}
+
+	removable, kept := g.CollectRemovable(cmd.packages)
+	for _, name := range removable {
+		p := g.Get(name)
+		if err = p.Uninstall(); err != nil {
+			ui.PrintfError("Uninstall %s: %v\n", p.Name, err)
+		} else {
+			ui.PrintfMinus("%s (%s)\n", p.Name, p.Version)
 		}
 	}
 
-	return p.Uninstall()
+	names := make([]string, 0, len(kept))
+	for name := range kept {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ui.PrintfWarning("Kept %s: required by %s\n", name, strings.Join(kept[name], ", "))
+	}
+
+	return nil
 }
 
 // DetectFlags analyzes the passed flags and fills in the variables associated
@@ -61,6 +109,13 @@ func (cmd *Uninstall) DetectFlags() error {
 
 // Execute removes the passed packages and all associated files.
 func (cmd *Uninstall) Execute() {
+	if cmd.collectDependencies {
+		if err := cmd.uninstallWithDependencies(); err != nil {
+			ui.PrintfError("Uninstall: %v\n", err)
+		}
+		return
+	}
+
 	for _, pkgName := range cmd.packages {
 		err := cmd.uninstall(pkgName)
 		if err != nil {
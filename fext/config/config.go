@@ -21,6 +21,16 @@ var (
 
 	PythonVersion string
 	PythonLibPath string // Path to python packages directory
+	DownloadPath  string // Path to the directory for downloaded distribution files
+	ScriptsPath   string // Path to the directory for executable scripts of packages
+	HeadersPath   string // Path to the directory for C header files of packages
+	DataPath      string // Path to the base directory for data files of packages
+
+	ConfigFilePath string // Path to the configuration file with the default options
+
+	PythonExecPath string // Path to the python interpreter used to run the scripts
+
+	Umask os.FileMode // File mode creation mask of the process
 
 	Command []string // Command and arguments specified by user
 	Flags   []string // Flags specified by user
@@ -40,6 +50,47 @@ func getPythonVersion() string {
 	return strings.TrimSpace(string(output[7:]))
 }
 
+// getDownloadPath returns the path to the directory for downloaded
+// distribution files in the user cache directory. If the cache directory is
+// unknown, the temporary directory is used instead.
+func getDownloadPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	return filepath.Join(cacheDir, "fext", "downloads")
+}
+
+// getConfigFilePath returns the path to the configuration file in the user
+// configuration directory. It can be overridden with the FEXT_CONFIG
+// environment variable. Returns an empty string if the configuration
+// directory is unknown.
+func getConfigFilePath() string {
+	if path := os.Getenv("FEXT_CONFIG"); path != "" {
+		return path
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, "fext", "config.toml")
+}
+
+// IsInPath checks if the directory is listed in the PATH environment variable,
+// so the executables in it can be run by name.
+func IsInPath(dir string) bool {
+	for _, path := range filepath.SplitList(os.Getenv("PATH")) {
+		if path != "" && filepath.Clean(path) == filepath.Clean(dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func getVirtualEnvPath() string {
 	return os.Getenv("VIRTUAL_ENV")
 }
@@ -90,5 +141,13 @@ func init() {
 		}
 	}
 
+	ScriptsPath = filepath.Clean(getScriptsPath())
+	HeadersPath = filepath.Clean(getHeadersPath())
+	DataPath = filepath.Clean(getDataPath())
+	PythonExecPath = getPythonExecPath()
+	DownloadPath = getDownloadPath()
+	ConfigFilePath = getConfigFilePath()
+	Umask = getUmask()
+
 	Command, Flags = parseArguments(os.Args[1:]) // The first argument is a name of executable file
 }
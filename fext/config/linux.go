@@ -7,6 +7,9 @@ import "C"
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
 )
 
 const (
@@ -30,3 +33,72 @@ func getPythonLib() string {
 func getPythonVenvLib() string {
 	return fmt.Sprintf("%s/lib/python3.%s/site-packages/", virtualEnvPath, GetPythonMinorVersion())
 }
+
+// getScriptsPath returns the path to the directory for executable scripts.
+// In the virtual environment, it is the "bin" directory of the environment.
+func getScriptsPath() string {
+	if virtualEnvPath != "" {
+		return virtualEnvPath + "/bin/"
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		panic(err)
+	}
+	return homePath + "/.local/bin/"
+}
+
+// getHeadersPath returns the path to the directory for C header files. Each
+// package puts its headers into a subdirectory named after it.
+func getHeadersPath() string {
+	if virtualEnvPath != "" {
+		return fmt.Sprintf("%s/include/site/python3.%s/", virtualEnvPath, GetPythonMinorVersion())
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s/.local/include/python3.%s/", homePath, GetPythonMinorVersion())
+}
+
+// getDataPath returns the base directory for data files. It is the root of
+// the virtual environment or the user base directory.
+func getDataPath() string {
+	if virtualEnvPath != "" {
+		return virtualEnvPath
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		panic(err)
+	}
+	return homePath + "/.local/"
+}
+
+// getPythonExecPath returns the absolute path to the interpreter of the
+// detected python version. If it can't be found, the name of the executable
+// is returned, so it is looked up in PATH when the script is run.
+func getPythonExecPath() string {
+	if virtualEnvPath != "" {
+		return virtualEnvPath + "/bin/python"
+	}
+
+	for _, name := range []string{"python3." + GetPythonMinorVersion(), pythonExec} {
+		if path, err := exec.LookPath(name); err == nil {
+			if path, err = filepath.Abs(path); err == nil {
+				return path
+			}
+		}
+	}
+
+	return pythonExec
+}
+
+// getUmask returns the file mode creation mask of the process. The mask can be
+// read only by setting it, so it is restored immediately.
+func getUmask() os.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return os.FileMode(mask)
+}
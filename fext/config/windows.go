@@ -5,6 +5,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 )
 
 const (
@@ -27,3 +29,68 @@ func getPythonLib() string {
 func getPythonVenvLib() string {
 	return virtualEnvPath + "\\Lib\\site-packages\\"
 }
+
+// getScriptsPath returns the path to the directory for executable scripts.
+// In the virtual environment, it is the "Scripts" directory of the
+// environment.
+func getScriptsPath() string {
+	if virtualEnvPath != "" {
+		return virtualEnvPath + "\\Scripts\\"
+	}
+
+	pathToAppData, err := os.UserConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s\\Python\\Python3%s\\Scripts\\", pathToAppData, GetPythonMinorVersion())
+}
+
+// getHeadersPath returns the path to the directory for C header files. Each
+// package puts its headers into a subdirectory named after it.
+func getHeadersPath() string {
+	if virtualEnvPath != "" {
+		return fmt.Sprintf("%s\\Include\\site\\python3.%s\\", virtualEnvPath, GetPythonMinorVersion())
+	}
+
+	pathToAppData, err := os.UserConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s\\Python\\Python3%s\\Include\\", pathToAppData, GetPythonMinorVersion())
+}
+
+// getDataPath returns the base directory for data files. It is the root of
+// the virtual environment or the user base directory.
+func getDataPath() string {
+	if virtualEnvPath != "" {
+		return virtualEnvPath
+	}
+
+	pathToAppData, err := os.UserConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	return pathToAppData + "\\Python\\"
+}
+
+// getPythonExecPath returns the absolute path to the python interpreter. If it
+// can't be found, the name of the executable is returned, so it is looked up
+// in PATH when the script is run.
+func getPythonExecPath() string {
+	if virtualEnvPath != "" {
+		return virtualEnvPath + "\\Scripts\\python.exe"
+	}
+
+	if path, err := exec.LookPath(pythonExec); err == nil {
+		if path, err = filepath.Abs(path); err == nil {
+			return path
+		}
+	}
+
+	return pythonExec
+}
+
+// getUmask returns zero, since Windows doesn't use the file mode creation mask.
+func getUmask() os.FileMode {
+	return 0
+}
@@ -34,3 +34,26 @@ func ParseExtraNames(s string) (string, []string, error) {
 
 	return s, nil, nil
 }
+
+// NormalizeName normalizes the package name according to PEP 503. All runs of
+// "-", "_" and "." are replaced with a single "-", and the name is converted to
+// lowercase.
+//
+//	NormalizeName("Foo.Bar__baz") => "foo-bar-baz"
+func NormalizeName(name string) string {
+	var b strings.Builder
+	var separator bool
+
+	for _, char := range strings.ToLower(name) {
+		if char == '-' || char == '_' || char == '.' {
+			separator = true
+			continue
+		} else if separator && b.Len() > 0 {
+			b.WriteRune('-')
+		}
+		separator = false
+		b.WriteRune(char)
+	}
+
+	return b.String()
+}
@@ -45,3 +45,9 @@ func TestParseExtraNamesInvalid(t *testing.T) {
 		assert.ErrorIs(t, err, ferror.SyntaxError)
 	}
 }
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "foo-bar-baz", NormalizeName("Foo.Bar__baz"))
+	assert.Equal(t, "foo-bar", NormalizeName("-foo-_-bar."))
+	assert.Equal(t, "foo", NormalizeName("FOO"))
+}
@@ -173,8 +173,13 @@ func compareMajorVersion(v1, v2 string) (bool, error) {
 // The version is parsed according to the PEP 440 standard, which means that the
 // semantic version cannot be compared using it. Comparison of pre-release
 // versions occurs only if they are specified in both versions. Otherwise, the
-// check will be skipped
+// check will be skipped. The arbitrary equality "===" compares the versions as
+// strings, without parsing them
 func CompareVersion(v1, op, v2 string) (bool, error) {
+	if op == "===" {
+		return strings.EqualFold(v1, v2), nil
+	}
+
 	res, err := compareVersion(v1, v2)
 	if err != nil {
 		return false, err
@@ -195,6 +200,13 @@ func CompareVersion(v1, op, v2 string) (bool, error) {
 	return false, nil
 }
 
+// ValidateVersion checks that the version can be parsed and compared with
+// other versions. It returns an error in case of syntax violation.
+func ValidateVersion(s string) error {
+	_, _, err := parseVersion(s)
+	return err
+}
+
 // isOperator checks if a rune corresponds to a specific operator symbol.
 func isOperator(char rune) bool {
 	for _, op := range operators {
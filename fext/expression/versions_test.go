@@ -12,6 +12,7 @@ var (
 		{"1.0.0", "==", "1.0.0"},
 		{"1.1.0", "==", "1.1"},
 		{"1.1", "==", "1.1"},
+		{"1.0-Custom", "===", "1.0-custom"},
 		{"1", "==", "1"},
 		{"2.0.1", ">=", "2.0.0"},
 		{"2.0.0", ">=", "2.0.0"},
@@ -67,6 +68,7 @@ var (
 		{"1.2.0", "!=", "1.1.*"},
 	}
 	compareVersionFalse = [][3]string{
+		{"1.0", "===", "1.0.0"},
 		{"1.0.0", "!=", "1.0.0"},
 		{"1.0.0", ">", "1.0.0"},
 		{"1.0.0", "<", "1.0.0"},
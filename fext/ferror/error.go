@@ -11,19 +11,23 @@ var (
 	// PackageAlreadyInstalled means that you are trying to install a package
 	// already installed in the system.
 	PackageAlreadyInstalled = errors.New("package already installed")
-	// PackageInLocalList means that the package installation attempt is being
-	// made for the second time. The previous suitable version has already been
-	// installed. The error arises during the comparison of the installed
-	// package version with the operators obtained from other dependent packages.
-	// To summarize, if this error occurs after comparing the list of operators,
-	// it means that reinstalling the package is not necessary. It is already
-	// present in the system and compatible with the other packages that use it.
-	PackageInLocalList = errors.New("package version compared and already installed")
 	// NoSuitableVersion means that no suitable version was found for the given query.
 	// This can be related to both the operators provided and the package's
 	// complete incompatibility with the current system (e.g., different Python
 	// version or unsupported platform).
 	NoSuitableVersion = errors.New("no suitable version")
+	// ResolutionTooDeep means that the dependency resolver has tried too many
+	// candidates without finding a set of versions compatible with each other.
+	ResolutionTooDeep = errors.New("dependency resolution is too deep")
+	// IncompatibleEnvironment means that the lock file is not intended for the
+	// current system, none of its environment markers are true.
+	IncompatibleEnvironment = errors.New("lock file is not compatible with the current environment")
+	// InstallationRolledBack means that the installation has failed, and all
+	// the changes made to the system have been reverted.
+	InstallationRolledBack = errors.New("installation failed, changes were rolled back")
+	// PackagesLocked means that another fext process is installing or
+	// removing the packages at the moment.
+	PackagesLocked = errors.New("another fext process is changing the packages")
 	// HelpFlag means that the help string for the given command needs to be
 	// displayed on the screen.
 	HelpFlag = errors.New("help flag")
@@ -87,3 +91,262 @@ type UnexpectedOperator struct {
 func (u *UnexpectedOperator) Error() string {
 	return "unexpected operator: " + u.Operator
 }
+
+// HashMismatch means that the digest of the downloaded file doesn't match the
+// one provided by the repository. The file may be corrupted or tampered with.
+type HashMismatch struct {
+	File     string
+	Expected string
+	Actual   string
+}
+
+func (e *HashMismatch) Error() string {
+	return "hash mismatch: " + e.File + ": expected " + e.Expected + ", got " + e.Actual
+}
+
+// UnsupportedHash means that the file digest is computed with an unknown or
+// insecure hash algorithm, so the file can't be verified.
+type UnsupportedHash struct {
+	Algorithm string
+}
+
+func (e *UnsupportedHash) Error() string {
+	return "unsupported hash algorithm: " + e.Algorithm
+}
+
+// MissingHashes means that the hash-checking mode is enabled, but no hashes
+// were provided for the package.
+type MissingHashes struct {
+	Package string
+}
+
+func (e *MissingHashes) Error() string {
+	return "hashes are required in hash-checking mode: " + e.Package
+}
+
+// UnpinnedRequirement means that the hash-checking mode is enabled, but the
+// package version is not pinned with "==".
+type UnpinnedRequirement struct {
+	Package string
+}
+
+func (e *UnpinnedRequirement) Error() string {
+	return "requirement must be pinned with \"==\" in hash-checking mode: " + e.Package
+}
+
+// PackageNotFound means that the repository doesn't know the package at all.
+type PackageNotFound struct {
+	Package string
+	Index   string
+}
+
+func (e *PackageNotFound) Error() string {
+	return "package not found: " + e.Package + " (" + e.Index + ")"
+}
+
+// InvalidIndexURL means that the repository URL is not an absolute HTTP(S)
+// URL, so the packages can't be looked up in it.
+type InvalidIndexURL struct {
+	URL string
+}
+
+func (e *InvalidIndexURL) Error() string {
+	return "invalid index URL: " + e.URL
+}
+
+// UnexpectedConfigKey means that the configuration file contains an unknown
+// setting, e.g. a misspelled one.
+type UnexpectedConfigKey struct {
+	Key string
+}
+
+func (e *UnexpectedConfigKey) Error() string {
+	return "unexpected configuration key: " + e.Key
+}
+
+// UnsupportedAPIVersion means that the repository responded in a version of
+// the Simple API whose major version is unknown.
+type UnsupportedAPIVersion struct {
+	Version string
+}
+
+func (e *UnsupportedAPIVersion) Error() string {
+	return "unsupported repository API version: " + e.Version
+}
+
+// MetadataMismatch means that the core metadata served by the repository
+// separately from the wheel differs from the METADATA file inside it, so the
+// dependencies could have been resolved incorrectly.
+type MetadataMismatch struct {
+	File string
+}
+
+func (e *MetadataMismatch) Error() string {
+	return "metadata doesn't match the distribution file: " + e.File
+}
+
+// OfflineDownload means that the distribution file is not available locally,
+// and it can't be downloaded since the network access is disabled.
+type OfflineDownload struct {
+	File string
+}
+
+func (e *OfflineDownload) Error() string {
+	return "unable to download in offline mode: " + e.File
+}
+
+// UnsupportedLockFile means that the lock file was written in an unknown
+// format version, e.g. by a newer version of fext.
+type UnsupportedLockFile struct {
+	Version string
+}
+
+func (e *UnsupportedLockFile) Error() string {
+	return "unsupported lock file version: " + e.Version
+}
+
+// NoCompatibleWheel means that the lock file doesn't list a wheel of the
+// package that can be installed on the current system.
+type NoCompatibleWheel struct {
+	Package string
+}
+
+func (e *NoCompatibleWheel) Error() string {
+	return "no compatible wheel in the lock file: " + e.Package
+}
+
+// IncompatibleFile means that the direct reference or the local path points
+// to a file that is not a wheel compatible with the current system, e.g. a
+// source distribution.
+type IncompatibleFile struct {
+	Package string
+	File    string
+}
+
+func (e *IncompatibleFile) Error() string {
+	return "not a wheel compatible with the current system: " + e.File
+}
+
+// PathTraversal means that the archive entry points outside the directory the
+// archive is extracted into, e.g. "../../.bashrc".
+type PathTraversal struct {
+	Path string
+}
+
+func (e *PathTraversal) Error() string {
+	return "archive entry points outside the target directory: " + e.Path
+}
+
+// AbsolutePath means that the archive entry has an absolute path, e.g.
+// "/etc/passwd".
+type AbsolutePath struct {
+	Path string
+}
+
+func (e *AbsolutePath) Error() string {
+	return "archive entry has an absolute path: " + e.Path
+}
+
+// SymlinkEntry means that the archive entry is a symbolic link. Links could
+// redirect other entries outside the target directory, so they are forbidden.
+type SymlinkEntry struct {
+	Path string
+}
+
+func (e *SymlinkEntry) Error() string {
+	return "archive entry is a symbolic link: " + e.Path
+}
+
+// DuplicateEntry means that the archive contains several entries with the
+// same path, so one of them would silently overwrite the other.
+type DuplicateEntry struct {
+	Path string
+}
+
+func (e *DuplicateEntry) Error() string {
+	return "duplicate archive entry: " + e.Path
+}
+
+// ArchiveTooLarge means that the total size of the extracted files exceeds
+// the limit. The archive may be a decompression bomb.
+type ArchiveTooLarge struct {
+	Size  string
+	Limit string
+}
+
+func (e *ArchiveTooLarge) Error() string {
+	return "archive is too large when extracted: " + e.Size + " bytes (limit " + e.Limit + ")"
+}
+
+// CompressionRatioTooHigh means that the archive entry is compressed
+// suspiciously well. The archive may be a decompression bomb.
+type CompressionRatioTooHigh struct {
+	Path  string
+	Ratio string
+}
+
+func (e *CompressionRatioTooHigh) Error() string {
+	return "archive entry has a suspicious compression ratio: " + e.Path + " (" + e.Ratio + ")"
+}
+
+// UnexpectedEntryPoint means that the entry point of the package is malformed,
+// e.g. the script doesn't refer to a callable object, or its name is not a
+// valid file name.
+type UnexpectedEntryPoint struct {
+	EntryPoint string
+}
+
+func (e *UnexpectedEntryPoint) Error() string {
+	return "unexpected entry point: " + e.EntryPoint
+}
+
+// UnexpectedScheme means that the data directory of the wheel contains a
+// subdirectory that doesn't match any installation scheme path.
+type UnexpectedScheme struct {
+	Scheme string
+}
+
+func (e *UnexpectedScheme) Error() string {
+	return "unexpected installation scheme: " + e.Scheme
+}
+
+// UnsupportedWheelVersion means that the wheel was built in a format whose
+// major version is unknown, so it can't be installed correctly.
+type UnsupportedWheelVersion struct {
+	Version string
+}
+
+func (e *UnsupportedWheelVersion) Error() string {
+	return "unsupported wheel version: " + e.Version
+}
+
+// MalformedWheelFile means that the WHEEL file of the archive is missing or
+// one of its fields has an invalid value.
+type MalformedWheelFile struct {
+	Field string
+}
+
+func (e *MalformedWheelFile) Error() string {
+	return "malformed WHEEL file: " + e.Field
+}
+
+// WheelTagMismatch means that the compatibility tags declared in the WHEEL
+// file differ from the ones in the wheel file name.
+type WheelTagMismatch struct {
+	Tag string
+}
+
+func (e *WheelTagMismatch) Error() string {
+	return "wheel tag doesn't match the file name: " + e.Tag
+}
+
+// RecordMismatch means that the content of the archive doesn't match its
+// RECORD file, e.g. the file is not listed or its digest is different. The
+// archive may be corrupted or tampered with.
+type RecordMismatch struct {
+	Path string
+}
+
+func (e *RecordMismatch) Error() string {
+	return "archive content doesn't match RECORD: " + e.Path
+}
@@ -7,9 +7,27 @@ import (
 	"github.com/fextpkg/cli/fext/command"
 	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/io/installer"
+	"github.com/fextpkg/cli/fext/io/web"
 	"github.com/fextpkg/cli/fext/ui"
 )
 
+// mutatingCommands are the commands that change the installed packages. Only
+// one fext process can run them at once.
+var mutatingCommands = map[string]bool{
+	"install":    true,
+	"i":          true,
+	"uninstall":  true,
+	"u":          true,
+	"autoremove": true,
+}
+
+// packagesLock is held until the process exits. The reference is kept, so
+// the file is not closed by the garbage collector, which would release the
+// lock.
+var packagesLock *io.FileLock
+
 type ICommand interface {
 	DetectFlags() error
 	Execute()
@@ -29,10 +47,16 @@ func getCommandInterface() (ICommand, func(), error) {
 		return command.InitInstall(args), ui.PrintHelpInstall, nil
 	case "uninstall", "u":
 		return command.InitUninstall(args), ui.PrintHelpUninstall, nil
+	case "autoremove":
+		return command.InitAutoremove(), ui.PrintHelpAutoremove, nil
+	case "lock", "l":
+		return command.InitLock(args), ui.PrintHelpLock, nil
 	case "freeze", "f":
 		return command.InitFreeze(), ui.PrintHelpFreeze, nil
 	case "show", "info":
-		return command.InitShowPackageInfo(args), nil, nil
+		return command.InitShowPackageInfo(args), ui.PrintHelpShow, nil
+	case "outdated":
+		return command.InitOutdated(), ui.PrintHelpOutdated, nil
 	case "check":
 		return command.InitCheckPackageHealth(), nil, nil
 	case "debug":
@@ -70,10 +94,37 @@ func executeCommand() {
 
 	cmd.Execute()
 }
+
+// lockPackages locks the installed packages for the command that changes
+// them, so it never interferes with another fext process. Then the
+// installation that was abruptly interrupted, e.g. when the process was
+// killed, is rolled back, so the packages are restored to their previous
+// state. The files left by interrupted downloads are removed as well.
+func lockPackages() {
+	var err error
+	if packagesLock, err = installer.LockPackages(); err != nil {
+		ui.Fatal("Unable to lock the packages:", err.Error())
+	}
+
+	recovered, err := installer.Recover()
+	if err != nil {
+		ui.Fatal("Unable to recover the interrupted installation:", err.Error())
+	} else if recovered {
+		ui.PrintfWarning("The interrupted installation has been rolled back\n")
+	}
+
+	if err = web.RemoveStrayDownloads(); err != nil {
+		ui.PrintfWarning("Unable to remove temporary files: %v\n", err)
+	}
+}
+
 func main() {
 	if len(config.Command) == 0 { // No arguments were passed
 		ui.PrintHelp()
 	} else {
+		if mutatingCommands[config.Command[0]] {
+			lockPackages()
+		}
 		executeCommand()
 	}
 }
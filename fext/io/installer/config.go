@@ -0,0 +1,72 @@
+package installer
+
+import (
+	"errors"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+)
+
+// configFile is the TOML configuration file with the default options of the
+// package lookup, e.g.:
+//
+//	index-url = "https://pypi.example.com/simple/"
+//	extra-index-urls = ["https://pypi.org/simple/"]
+//
+//	[package-indexes]
+//	internal-lib = "https://pypi.example.com/simple/"
+type configFile struct {
+	IndexURL       string            `toml:"index-url"`
+	ExtraIndexURLs []string          `toml:"extra-index-urls"`
+	PackageIndexes map[string]string `toml:"package-indexes"`
+}
+
+// ReadConfigFile applies the settings of the configuration file to the
+// options. The file is optional, the options are left unchanged if it doesn't
+// exist. It should be read before the command line flags are applied, so the
+// flags take precedence.
+// Returns ferror.UnexpectedConfigKey if the file contains an unknown setting,
+// or ferror.InvalidIndexURL if any index URL is invalid.
+func (opt *Options) ReadConfigFile(fileName string) error {
+	if fileName == "" {
+		return nil
+	}
+
+	var c configFile
+	meta, err := toml.DecodeFile(fileName, &c)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return &ferror.UnexpectedConfigKey{Key: undecoded[0].String()}
+	}
+
+	if c.IndexURL != "" {
+		if err = web.ValidateIndexURL(c.IndexURL); err != nil {
+			return err
+		}
+		opt.IndexURL = c.IndexURL
+	}
+	for _, indexURL := range c.ExtraIndexURLs {
+		if err = web.ValidateIndexURL(indexURL); err != nil {
+			return err
+		}
+		opt.ExtraIndexURLs = append(opt.ExtraIndexURLs, indexURL)
+	}
+	if opt.PackageIndexes == nil && len(c.PackageIndexes) > 0 {
+		opt.PackageIndexes = map[string]string{}
+	}
+	for pkgName, indexURL := range c.PackageIndexes {
+		if err = web.ValidateIndexURL(indexURL); err != nil {
+			return err
+		}
+		opt.PackageIndexes[pkgName] = indexURL
+	}
+
+	return nil
+}
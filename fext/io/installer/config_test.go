@@ -0,0 +1,58 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+)
+
+// writeConfigFile writes the configuration file to the temporary directory
+// and returns the path to it.
+func writeConfigFile(t *testing.T, content string) string {
+	fileName := filepath.Join(t.TempDir(), "config.toml")
+	assert.Nil(t, os.WriteFile(fileName, []byte(content), 0644))
+
+	return fileName
+}
+
+func TestOptions_ReadConfigFile(t *testing.T) {
+	opt := DefaultOptions()
+	opt.ExtraIndexURLs = []string{"https://flag.example.com/simple/"}
+	fileName := writeConfigFile(t, `
+index-url = "https://pypi.example.com/simple/"
+extra-index-urls = ["https://pypi.org/simple/"]
+
+[package-indexes]
+internal-lib = "https://pypi.example.com/simple/"
+`)
+
+	assert.Nil(t, opt.ReadConfigFile(fileName))
+	assert.Equal(t, "https://pypi.example.com/simple/", opt.IndexURL)
+	assert.Equal(t, []string{"https://flag.example.com/simple/", "https://pypi.org/simple/"}, opt.ExtraIndexURLs)
+	assert.Equal(t, map[string]string{"internal-lib": "https://pypi.example.com/simple/"}, opt.PackageIndexes)
+
+	// The missing file is not an error
+	opt = DefaultOptions()
+	assert.Nil(t, opt.ReadConfigFile(filepath.Join(t.TempDir(), "config.toml")))
+	assert.Nil(t, opt.ReadConfigFile(""))
+	assert.Equal(t, web.DefaultIndexURL, opt.IndexURL)
+}
+
+func TestOptions_ReadConfigFile_Invalid(t *testing.T) {
+	var unexpected *ferror.UnexpectedConfigKey
+	err := DefaultOptions().ReadConfigFile(writeConfigFile(t, `extra-index-url = "https://pypi.org/simple/"`))
+	assert.ErrorAs(t, err, &unexpected)
+	assert.Equal(t, "extra-index-url", unexpected.Key)
+
+	var invalid *ferror.InvalidIndexURL
+	err = DefaultOptions().ReadConfigFile(writeConfigFile(t, "[package-indexes]\npkg = \"pypi.org/simple\""))
+	assert.ErrorAs(t, err, &invalid)
+
+	err = DefaultOptions().ReadConfigFile(writeConfigFile(t, "index-url = "))
+	assert.NotNil(t, err)
+}
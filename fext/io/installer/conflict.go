@@ -0,0 +1,225 @@
+package installer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// requirement is a constraint put forward for a package, prepared for the
+// conflict explanation.
+type requirement struct {
+	// Candidate that put forward the constraint. It is nil if the package
+	// was requested by the user
+	origin *candidate
+	// Chain of candidates that led to the origin, starting from the package
+	// requested by the user
+	chain []string
+	// Package name with the conditions, e.g. "b<1.5,>=1"
+	spec string
+	// Determines if none of the package candidates satisfies this constraint
+	// on its own
+	unsatisfiable bool
+}
+
+// describe returns the origin of the requirement along with the chain of
+// packages that brought it into the resolution.
+func (r requirement) describe() string {
+	if len(r.chain) == 0 {
+		return r.origin.String()
+	}
+
+	return r.origin.String() + " (via " + strings.Join(r.chain, " -> ") + ")"
+}
+
+// conflict means that no candidate of the package satisfies the constraints
+// put forward for it. The resolver uses the causes to decide how far it
+// should backtrack, and the rest of the attributes are used to explain to the
+// user what went wrong.
+type conflict struct {
+	pkgName string
+	// Names of the pinned packages that led to the conflict. Choosing another
+	// candidate for any other package won't resolve it
+	causes map[string]bool
+	// Constraints put forward for the package
+	requirements []requirement
+	// Candidate that had already been pinned for the package when a new
+	// incompatible constraint appeared
+	pinned *candidate
+	// Conflicts that the pinned candidates of the package led to
+	rejected []*conflict
+	// Pinned candidates of the causes
+	context []string
+}
+
+func (c *conflict) Error() string {
+	return "dependencies can't be resolved:\n\t" + strings.Join(c.explain(nil, map[string]bool{}), "\n\t")
+}
+
+func (c *conflict) Unwrap() error {
+	return ferror.NoSuitableVersion
+}
+
+// explain appends the derivation of the conflict to the lines. The conflicts
+// of the rejected candidates come first, since the conflict follows from
+// them. Lines that have already been seen are not repeated.
+func (c *conflict) explain(lines []string, seen map[string]bool) []string {
+	for _, reason := range c.rejected {
+		lines = reason.explain(lines, seen)
+	}
+
+	line := c.summary()
+	if !seen[line] {
+		seen[line] = true
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// summary describes the conflict in a single sentence, e.g. "a 2.0 requires
+// b<1.5, c 3.1 requires b>=2, so a 2.0 and c 3.1 can't both be installed".
+func (c *conflict) summary() string {
+	if len(c.rejected) > 0 {
+		line := "so no version of " + c.pkgName + " can be installed"
+		if len(c.context) > 0 {
+			line += " along with " + joinNames(c.context)
+		}
+		return line
+	}
+
+	var parts, participants []string
+	var requested bool
+	for _, r := range c.requirements {
+		if r.origin == nil {
+			parts = append(parts, r.spec+" is requested")
+			requested = true
+		} else {
+			parts = append(parts, r.describe()+" requires "+r.spec)
+			if !contains(participants, r.origin.String()) {
+				participants = append(participants, r.origin.String())
+			}
+		}
+	}
+
+	// Requirements that can't be satisfied on their own don't need to be
+	// compared with the others
+	var unsatisfiable []string
+	for i, r := range c.requirements {
+		if r.unsatisfiable {
+			unsatisfiable = append(unsatisfiable, parts[i])
+		}
+	}
+	if len(unsatisfiable) > 0 {
+		return strings.Join(unsatisfiable, ", ") + ", but no suitable version of " + c.pkgName + " was found"
+	}
+
+	line := strings.Join(parts, ", ")
+	switch {
+	case c.pinned != nil:
+		return line + ", so " + joinNames(participants) + " can't be installed along with " + c.pinned.String()
+	case len(participants) == 2:
+		return line + ", so " + joinNames(participants) + " can't both be installed"
+	case len(participants) > 2:
+		return line + ", so " + joinNames(participants) + " can't be installed together"
+	case len(participants) == 1 && requested:
+		return line + ", so " + participants[0] + " can't be installed"
+	default:
+		return line + ", but no version of " + c.pkgName + " satisfies them all"
+	}
+}
+
+// newConflict creates a conflict for the package in the current state of the
+// resolution. If the package is already pinned, only the constraints that the
+// pinned version doesn't satisfy are described. Otherwise, each constraint is
+// checked against the candidates to find the unsatisfiable ones.
+func (s *resolution) newConflict(pkgName string, causes map[string]bool, candidates []*candidate) *conflict {
+	cf := &conflict{
+		pkgName: pkgName,
+		causes:  causes,
+		pinned:  s.pins[pkgName],
+	}
+
+	for _, c := range s.constraints[pkgName] {
+		r := requirement{
+			origin: c.origin,
+			spec:   pkgName + formatConditions(c.conditions),
+		}
+		if c.origin != nil {
+			r.chain = s.getChain(c.origin.name)
+		}
+
+		if cf.pinned != nil {
+			compatible, _ := satisfies(cf.pinned.version, []constraint{c})
+			if compatible {
+				continue
+			}
+		} else {
+			r.unsatisfiable = true
+			for _, candidate := range candidates {
+				if compatible, _ := satisfies(candidate.version, []constraint{c}); compatible {
+					r.unsatisfiable = false
+					break
+				}
+			}
+		}
+		cf.requirements = append(cf.requirements, r)
+	}
+
+	for name := range causes {
+		if pinned, ok := s.pins[name]; ok && name != pkgName {
+			cf.context = append(cf.context, pinned.String())
+		}
+	}
+	sort.Strings(cf.context)
+
+	return cf
+}
+
+// getChain returns the chain of pinned candidates that brought the package
+// into the resolution, starting from the package requested by the user. The
+// package itself is not included. For each package, only the first constraint
+// put forward for it is taken into account.
+func (s *resolution) getChain(pkgName string) []string {
+	var chain []string
+	seen := map[string]bool{pkgName: true}
+
+	for {
+		constraints := s.constraints[pkgName]
+		if len(constraints) == 0 || constraints[0].origin == nil {
+			break
+		}
+
+		origin := constraints[0].origin
+		if seen[origin.name] {
+			break
+		}
+		seen[origin.name] = true
+		chain = append([]string{origin.String()}, chain...)
+		pkgName = origin.name
+	}
+
+	return chain
+}
+
+// formatConditions joins the conditions into a version specifier, e.g.
+// "<2,>=1". Returns an empty string if there are no conditions.
+func formatConditions(conditions []expression.Condition) string {
+	var specs []string
+	for _, c := range conditions {
+		specs = append(specs, c.Operator+c.Value)
+	}
+
+	return strings.Join(specs, ",")
+}
+
+// joinNames joins the names in an enumeration, e.g. "a, b and c".
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
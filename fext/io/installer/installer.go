@@ -1,9 +1,14 @@
 package installer
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/expression"
 	"github.com/fextpkg/cli/fext/ferror"
 	"github.com/fextpkg/cli/fext/io"
@@ -18,6 +23,30 @@ type Options struct {
 
 	// Output only error messages
 	QuietMode bool
+
+	// Install only packages pinned to an exact version with the known hashes
+	// of their distribution files. It is enabled automatically if any query
+	// has hashes
+	RequireHashes bool
+
+	// Local directories with the distribution files, which are looked up
+	// along with the index
+	FindLinks []string
+
+	// Do not access the network. Packages are installed only from the
+	// find-links directories and the local files
+	Offline bool
+
+	// URL of the main package index in the Simple API format
+	IndexURL string
+
+	// URLs of the additional package indexes, which are looked up along with
+	// the main one
+	ExtraIndexURLs []string
+
+	// URLs of the indexes the packages are pinned to, by the package name.
+	// Pinned packages are looked up only in their index
+	PackageIndexes map[string]string
 }
 
 // DefaultOptions returns an Options struct with default parameters
@@ -25,271 +54,489 @@ func DefaultOptions() *Options {
 	return &Options{
 		NoDependencies: false,
 		QuietMode:      false,
+		RequireHashes:  false,
+		FindLinks:      nil,
+		Offline:        false,
+		IndexURL:       web.DefaultIndexURL,
+		ExtraIndexURLs: nil,
+		PackageIndexes: map[string]string{},
 	}
 }
 
 type Installer struct {
-	// Installed packages
-	local map[string]*Query
-	// Prepared installation queries for the packages, including names,
-	// conditional operators, and versions
-	queue chan *Query
+	// Installation queries for the packages requested by the user, including
+	// names, conditional operators, and extra names
+	queries []*Query
+	// Requirements as they were passed by the user
+	requirements []string
+	// Normalized names of the packages requested by the user, which are
+	// marked with the "REQUESTED" file
+	requested map[string]bool
+	// Source of the package candidates for the resolver
+	source *indexSource
 
 	opt *Options
 }
 
-// updateLocal updates or adds conditional operators to maintain compatibility
-// with the other packages to be installed.
-func (i *Installer) updateLocal(newQuery *Query) {
-	query, exist := i.local[newQuery.pkgName]
-	if exist {
-		query.conditions = append(query.conditions, newQuery.conditions...)
-	} else {
-		i.local[newQuery.pkgName] = newQuery
+// isRequested checks if the package was requested by the user without any
+// extra names.
+func (i *Installer) isRequested(pkgName string) bool {
+	for _, q := range i.queries {
+		if expression.NormalizeName(q.pkgName) == pkgName && len(q.extraNames) == 0 {
+			return true
+		}
 	}
+
+	return false
 }
 
-// checkCompatibility checks the compatibility of the package version with
-// other packages that have been installed in the current session. It compares
-// the existing conditions with the ones provided.
-// It returns a boolean value indicating the compatibility or false if the
-// package was not found in local. If an error occurs during the comparison of
-// operators, it throws an error.
-func (i *Installer) checkCompatibility(pkgName, version string, cond []expression.Condition) (bool, error) {
-	query, exist := i.local[pkgName]
-	if exist {
-		return expression.CompareConditions(version, append(query.conditions, cond...))
+// prepareStaged completes the staged package before it is moved into place.
+// It makes a note that fext installed the package, writes the given files to
+// the meta-directory, moves the content of the
+// wheel data directory to the installation scheme paths, writes the launchers
+// of the package scripts, notes the executable files, and rewrites the RECORD
+// file to list all the files as they are installed.
+// Returns the targets with the files installed outside config.PythonLibPath,
+// ferror.PackageDirectoryMissing if the package has no meta-directory, or
+// ferror.UnexpectedScheme if the data directory is malformed.
+func prepareStaged(tx *transaction, staged string, metaFiles map[string][]byte) ([]*stagedTarget, error) {
+	metaDirs, err := filepath.Glob(filepath.Join(staged, "*.dist-info"))
+	if err != nil {
+		return nil, err
+	} else if len(metaDirs) != 1 {
+		return nil, ferror.PackageDirectoryMissing
 	}
+	metaDirName := filepath.Base(metaDirs[0])
 
-	return false, nil
-}
+	if err = io.CreateInstallerFile(metaDirs[0]); err != nil {
+		return nil, err
+	}
+	for name, data := range metaFiles {
+		if err = os.WriteFile(filepath.Join(metaDirs[0], name), data, 0666); err != nil {
+			return nil, err
+		}
+	}
 
-// isInstalled checks if the package has been installed within the
-// current session.
-func (i *Installer) isInstalled(pkgName string) bool {
-	_, exist := i.local[pkgName]
-	return exist
-}
+	// Headers are placed into the directory named after the package
+	distName, _, _ := strings.Cut(metaDirName, "-")
+	targets := map[string]*stagedTarget{
+		schemeScripts: tx.newTarget(config.ScriptsPath),
+		schemeHeaders: tx.newTarget(filepath.Join(config.HeadersPath, distName)),
+		schemeData:    tx.newTarget(config.DataPath),
+	}
 
-// Extracts extra dependencies from the packages, creates a new query object
-// with them, and adds it to the queue. If an extra package name is provided
-// but not locally installed, the query object is copied to the "extraNames"
-// attribute, and the original query is cleared of extra names.
-// It returns an error if the extra packages are not found or if there is any
-// other issue related to processing the package metadata.
-func (i *Installer) supply(queries []*Query) error {
-	var q *Query
-	for len(queries) > 0 {
-		q = queries[0]
-		queries = queries[1:]
-
-		pkgName, extra, err := expression.ParseExtraNames(q.pkgName)
+	dataDir := filepath.Join(staged, getDataDirectoryName(metaDirName))
+	if err = installDataDirectory(dataDir, staged, targets); err != nil {
+		return nil, err
+	}
+	if err = writeLaunchers(targets[schemeScripts].root, metaDirs[0]); err != nil {
+		return nil, err
+	}
+
+	entries, err := pkg.ScanRecord(staged, metaDirName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The executable bits are noted, so they can be checked afterward. The
+	// note shipped with the wheel, if any, is replaced
+	executablesPath := metaDirName + "/" + pkg.ExecutablesFileName
+	var executables []string
+	scanned := entries
+	entries = nil
+	for _, entry := range scanned {
+		if entry.Path == executablesPath {
+			continue
+		}
+		entries = append(entries, entry)
+
+		info, err := os.Lstat(filepath.Join(staged, filepath.FromSlash(entry.Path)))
 		if err != nil {
+			return nil, err
+		} else if info.Mode()&0111 != 0 {
+			executables = append(executables, entry.Path)
+		}
+	}
+
+	var used []*stagedTarget
+	for _, key := range []string{schemeScripts, schemeHeaders, schemeData} {
+		targetEntries, targetExecutables, err := targets[key].record()
+		if err != nil {
+			return nil, err
+		} else if len(targetEntries) > 0 {
+			entries = append(entries, targetEntries...)
+			executables = append(executables, targetExecutables...)
+			used = append(used, targets[key])
+		}
+	}
+
+	entry, err := pkg.WriteExecutables(staged, metaDirName, executables)
+	if err != nil {
+		return nil, err
+	}
+
+	return used, pkg.WriteRecord(staged, metaDirName, append(entries, entry))
+}
+
+// writeLaunchers writes the launchers of the console and GUI scripts listed in
+// the "entry_points.txt" of the package to the directory.
+// Returns ferror.UnexpectedEntryPoint if any entry point is malformed.
+func writeLaunchers(dir, metaDirPath string) error {
+	entryPoints, err := pkg.ReadEntryPoints(filepath.Join(metaDirPath, pkg.EntryPointsFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, e := range entryPoints {
+		if !e.IsScript() {
+			continue
+		}
+
+		if _, err = io.WriteLauncher(dir, e.Name, e.Module, e.Object, e.Group == pkg.GUIScripts); err != nil {
 			return err
-		} else if len(extra) > 0 {
-			extraDeps, err := getPackageExtras(pkgName, extra)
-			if err != nil {
-				if !errors.Is(err, ferror.PackageDirectoryMissing) {
-					return err
-				}
-				// If a locally installed package is not found, but we are
-				// attempting to install its extra dependencies, an error would
-				// normally occur. To prevent this error and maintain backward
-				// compatibility, we employ a workaround by duplicating the
-				// query object without the extra names, and instead add the
-				// current query to the "extraNames" attribute. Once the
-				// installation is complete, we can retrieve and process the
-				// extra dependencies accordingly
-				q.extraNames = copyQuery(q)
-			} else {
-				// Adding extra dependencies to the queue for further processing,
-				// as they may also have additional extra dependencies within them
-				queries = append(queries, extraDeps...)
-				if len(q.conditions) == 0 {
-					// If no installation conditions are specified,
-					// it indicates that the package is already installed.
-					// Hence, there is no need for additional installation
-					continue
-				}
-			}
-			// Replacing the package name with a clean one,
-			// excluding any extra names
-			q.pkgName = pkgName
 		}
-		i.queue <- q
 	}
 
 	return nil
 }
 
-// Fetches the available versions for installation, selects the suitable
-// version based on the provided query attributes, downloads and unpacks the
-// package into the config.PythonLibPath.
-// It returns the package dependencies or an error if any occurs.
-func (i *Installer) install(query *Query) ([]pkg.Dependency, error) {
-	// Creating a new request
-	req := web.NewRequest(query.pkgName, query.conditions)
+// validateWheel checks the WHEEL and RECORD files of the candidate's wheel
+// before it is extracted. The wheel of a newer minor format version is
+// installed with a warning. If the dependencies were resolved using the core
+// metadata served by the repository, it must match the METADATA file of the
+// wheel exactly.
+// Returns ferror.UnsupportedWheelVersion if the major format version is
+// unknown, ferror.WheelTagMismatch if the declared tags differ from the file
+// name, ferror.MetadataMismatch if the metadata is different, or
+// ferror.RecordMismatch if the archive doesn't match its RECORD.
+func validateWheel(c *candidate) error {
+	info, err := io.ReadWheelInfo(c.filePath)
+	if err != nil {
+		return err
+	}
 
-	// Retrieving the necessary version based on the provided parameters
-	version, link, err := req.GetPackageData()
+	newer, err := info.CheckVersion()
 	if err != nil {
-		return nil, err
+		return err
+	} else if newer {
+		ui.PrintfWarning("%s: wheel version %s is newer than supported\n", c.remote.FileName, info.Version)
 	}
 
-	// First, check if the package is installed locally
-	p, err := pkg.Load(query.pkgName)
-	if err == nil {
-		compatible, err := i.checkCompatibility(query.pkgName, p.Version, query.conditions)
+	if err = info.CheckTags(web.GetWheelTags(c.remote.FileName)); err != nil {
+		return err
+	}
+
+	if c.indexMetadata != nil {
+		data, err := io.ReadPackageMetadata(c.filePath)
+		if err != nil {
+			return err
+		} else if !bytes.Equal(data, c.indexMetadata) {
+			return &ferror.MetadataMismatch{File: c.remote.FileName}
+		}
+	}
+
+	return io.VerifyRecord(c.filePath)
+}
+
+// getMetaFiles returns the content of the files written to the
+// meta-directory of the candidate's package, by the file name. The requested
+// package is marked with the "REQUESTED" file, the dependency with the
+// "FEXT_DEPENDENCY" one, and the package installed from the direct reference
+// gets the "direct_url.json" file (PEP 610).
+func getMetaFiles(c *candidate, requested bool) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if requested {
+		files[pkg.RequestedFileName] = nil
+	} else {
+		files[pkg.DependencyFileName] = nil
+	}
+
+	if c.directURL != "" {
+		digest, err := web.ComputeDigest(c.filePath, "sha256")
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(pkg.DirectURL{
+			URL:         c.directURL,
+			ArchiveInfo: &pkg.ArchiveInfo{Hashes: map[string]string{"sha256": digest}},
+		})
 		if err != nil {
-			// An error occurred while comparing operators
 			return nil, err
-		} else if compatible {
-			// The package is already installed and compatible with other
-			// packages that rely on it. Therefore, it is not necessary to
-			// reinstall it
-			return nil, ferror.PackageInLocalList
-		} else if version == p.Version && !i.isInstalled(query.pkgName) {
-			// This is the initial request for package installation.
-			// We need to provide a meaningful error to explain what occurred
-			return nil, ferror.PackageAlreadyInstalled
-		} else {
-			// The package is installed, but the version is not suitable.
-			// Remove the package and proceed with installing the required version
-			if err = p.Uninstall(); err != nil {
-				return nil, err
-			}
 		}
+		files[pkg.DirectURLFileName] = data
 	}
 
-	// Commencing package download
-	filePath, err := req.DownloadPackage(link)
-	if err != nil {
+	return files, nil
+}
+
+// install extracts the distribution file of the candidate into the staging
+// directory of the transaction, then moves it into the config.PythonLibPath.
+// If another version of the package is installed, its files are moved aside
+// first.
+// It returns the installed package, or an error if any occurs.
+func (i *Installer) install(tx *transaction, c *candidate) (*pkg.Package, error) {
+	// The file has usually been downloaded by the resolver to read the
+	// package metadata
+	if err := i.source.download(c); err != nil {
 		return nil, err
 	}
 
-	// Unpacking the installed file
-	if err = io.ExtractPackage(filePath); err != nil {
+	if err := validateWheel(c); err != nil {
 		return nil, err
 	}
-	// Remove downloaded file
-	if err = os.RemoveAll(filePath); err != nil {
+
+	staged, err := tx.stage(c.filePath)
+	if err != nil {
 		return nil, err
 	}
 
-	// Finally, we ensure that the package is installed correctly
-	p, err = pkg.Load(query.pkgName)
+	// Check if the package is installed locally. The package requested
+	// before stays requested after the upgrade
+	requested := i.requested[c.name]
+	p, err := pkg.Load(c.name)
+	if err == nil {
+		requested = requested || p.IsRequested()
+	} else if !errors.Is(err, ferror.PackageDirectoryMissing) {
+		return nil, err
+	}
+
+	metaFiles, err := getMetaFiles(c, requested)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := prepareStaged(tx, staged, metaFiles)
 	if err != nil {
 		return nil, err
 	}
 
-	// Make a note that fext installed this package
-	if err = io.CreateInstallerFile(p.GetMetaDirectoryPath()); err != nil {
+	if p != nil {
+		// The package is installed, but the version is not suitable.
+		// Remove the package and proceed with installing the required version
+		files, err := p.GetFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, fileName := range files {
+			if err = tx.remove(fileName); err != nil {
+				return nil, err
+			}
+		}
+		// The directories left empty are removed as well, but the ones shared
+		// with other packages, e.g. namespace packages, are kept
+		for _, dir := range pkg.GetParentDirectories(files) {
+			if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+				if err = tx.remove(dir); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// The package itself is moved last, so it becomes visible only when all
+	// its files are in place
+	for _, t := range targets {
+		if err = tx.move(t.dir, t.dst); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.move(staged, config.PythonLibPath); err != nil {
 		return nil, err
 	}
 
-	return p.GetDependencies()
+	// Finally, we ensure that the package is installed correctly
+	return pkg.Load(c.name)
 }
 
-// Retrieves a package from the queue and starts its installation along
-// with its dependencies. It also installs extra dependencies if they were
-// provided. The output is displayed in stdout. If Options.QuietMode if set to
-// true, success messages will not be displayed.
-func (i *Installer) process() {
-	for len(i.queue) > 0 {
-		q, open := <-i.queue
-		if !open {
-			// Handling exceptional cases when we abruptly close the channel
-			break
+// prepareHashes passes the hashes of the queries to the candidate source. If
+// any query has hashes, the hash-checking mode is enabled. In this mode, each
+// query must be pinned to an exact version or a direct reference, and have at
+// least one hash.
+// Returns ferror.UnpinnedRequirement or ferror.MissingHashes if the query
+// doesn't meet the requirements.
+func (i *Installer) prepareHashes() error {
+	for _, q := range i.queries {
+		if len(q.hashes) > 0 {
+			i.opt.RequireHashes = true
 		}
+	}
+	if !i.opt.RequireHashes {
+		return nil
+	}
 
-		dependencies, err := i.install(q)
-		// Update conditions even if an error occurs, because there is hope for
-		// a subsequent installation request.
-		i.updateLocal(q)
-		if err != nil {
-			if !errors.Is(err, ferror.PackageInLocalList) && !(errors.Is(err, ferror.PackageAlreadyInstalled) && q.isDependency) {
-				// The condition is passed only if the package has not been
-				// installed before within the current session. Or if another
-				// error is received and the package is not a dependency.
-				// If the package is a dependency and is already installed,
-				// there is no point in displaying an error message. Otherwise,
-				// there is no need to conceal the error
-				ui.PrintfMinus("%s (%v)\n", q.pkgName, err)
-			}
-			continue
+	for _, q := range i.queries {
+		if !q.isPinned() && q.url == "" {
+			// The direct reference is pinned to the file itself
+			return &ferror.UnpinnedRequirement{Package: q.pkgName}
+		} else if len(q.hashes) == 0 {
+			return &ferror.MissingHashes{Package: q.pkgName}
 		}
 
-		if !i.opt.QuietMode {
-			// Displaying a success message only if the quiet mode is not enabled
-			ui.PrintlnPlus(q.pkgName)
+		pkgName := expression.NormalizeName(q.pkgName)
+		i.source.hashes[pkgName] = append(i.source.hashes[pkgName], q.hashes...)
+	}
+	i.source.requireHashes = true
+
+	return nil
+}
+
+// apply installs the candidates in the given order within a single
+// transaction. Installed candidates are skipped, but the requested ones that
+// were installed as dependencies are marked requested. If any package fails to
+// install, or the process is interrupted, all the changes are rolled back. The
+// output is displayed in stdout. If Options.QuietMode is set to true, success
+// messages will not be displayed.
+// Returns any error returned by download before the transaction starts, or
+// ferror.InstallationRolledBack if the installation has failed.
+func (i *Installer) apply(candidates []*candidate) error {
+	var pending []*candidate
+	for _, c := range candidates {
+		if !c.isInstalled() {
+			pending = append(pending, c)
+			continue
 		}
 
-		if !i.opt.NoDependencies {
-			// Installing the acquired package dependencies during installation
-			err = i.supply(extraDependenciesToQuery(dependencies))
-			if err != nil {
-				ui.PrintfMinus("%s deps (%s)\n", q.pkgName, err)
+		if i.requested[c.name] && !c.metadata.IsRequested() {
+			if err := c.metadata.MarkRequested(); err != nil {
+				return err
 			}
 		}
+		if i.isRequested(c.name) {
+			// This is the initial request for package installation.
+			// We need to provide a meaningful error to explain what
+			// occurred
+			ui.PrintfMinus("%s (%v)\n", c.metadata.Name, ferror.PackageAlreadyInstalled)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
 
-		if q.extraNames != nil {
-			// Preparing extra dependencies for installation in this run due to
-			// their absence in the system during the previous attempt
-			err = i.supply([]*Query{q.extraNames})
-			if err != nil {
-				ui.PrintfMinus("%s extras (%s)\n", q.pkgName, err)
+	// The files are downloaded and verified before anything is changed, so
+	// the missing or mismatching hashes never start the transaction
+	for _, c := range pending {
+		if err := i.source.download(c); err != nil {
+			return err
+		}
+	}
+
+	tx, err := beginTransaction()
+	if err != nil {
+		return err
+	}
+	stop := tx.handleInterrupt()
+	defer stop()
+
+	var installed []string
+	for _, c := range pending {
+		warnYanked(c)
+		p, err := i.install(tx, c)
+		if err != nil {
+			ui.PrintfMinus("%s (%v)\n", c.name, err)
+			if err = tx.rollback(); err != nil {
+				return err
 			}
+			return ferror.InstallationRolledBack
 		}
+		installed = append(installed, p.Name)
 	}
-}
 
-// InitializePackages converts package names into a query queue and adds them
-// to the queue using the supply method.
-// It returns any error returned by the supply method.
-func (i *Installer) InitializePackages(packages []string) error {
-	var q []*Query
-	for _, pkgName := range packages {
-		q = append(q, newRawQuery(pkgName))
+	if err = tx.commit(); err != nil {
+		return err
 	}
 
-	return i.supply(q)
-}
+	if !i.opt.QuietMode {
+		// Displaying success messages only if the quiet mode is not enabled
+		for _, name := range installed {
+			ui.PrintlnPlus(name)
+		}
+	}
 
-// Install starts the package installing loop
-func (i *Installer) Install() {
-	i.process()
-	close(i.queue)
+	return nil
 }
 
-func NewInstaller(opt *Options) *Installer {
-	return &Installer{
-		local: map[string]*Query{},
-		queue: make(chan *Query, 512),
-		opt:   opt,
+// process resolves the dependencies of the requested packages and installs
+// the pinned versions that are not installed yet. Nothing is installed if the
+// resolution fails.
+// It returns an error if the resolution or the installation fails.
+func (i *Installer) process() error {
+	if err := i.prepareHashes(); err != nil {
+		return err
 	}
-}
 
-// getPackageExtras gets the extra dependencies of the package and wraps them in
-// a query list. Returns ferror.MissingExtra if extra name not found
-func getPackageExtras(pkgName string, extraNames []string) ([]*Query, error) {
-	var queries []*Query
-	p, err := pkg.Load(pkgName)
+	r := newResolver(i.source, i.opt.NoDependencies)
+	s, err := r.resolveQueries(i.queries)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	candidates := make([]*candidate, 0, len(s.order))
+	for _, pkgName := range s.order {
+		candidates = append(candidates, s.pins[pkgName])
 	}
+	return i.apply(candidates)
+}
 
-	for _, extraName := range extraNames {
-		extraDeps, err := p.GetExtraDependencies(extraName)
+// InitializePackages converts package names into installation queries. Each
+// package name can be followed by the "--hash" options. Direct references
+// (PEP 508) and paths to the local wheel files are installed from the files
+// they point to, without looking up the index. The packages whose markers
+// are false in the current environment are skipped.
+// It returns ferror.SyntaxError if extra names of any package are invalid, or
+// an error if the options are invalid.
+func (i *Installer) InitializePackages(packages []string) error {
+	for _, pkgName := range joinDirectReferences(packages) {
+		q, err := newRawQuery(pkgName, false)
 		if err != nil {
-			return nil, err
-		} else if !p.HasExtraName(extraName) {
-			return nil, &ferror.MissingExtra{Name: extraName}
+			return err
 		}
-
-		for _, dep := range extraDeps {
-			queries = append(queries, newQuery(dep.PackageName, dep.Conditions, true))
+		if q.markers != "" {
+			required, err := expression.CompareMarkers(q.markers)
+			if err != nil {
+				return err
+			} else if !required {
+				// The package is not required in the current environment
+				continue
+			}
+		}
+		i.queries = append(i.queries, q)
+		i.requirements = append(i.requirements, pkgName)
+		i.requested[expression.NormalizeName(q.pkgName)] = true
+		if q.url != "" {
+			i.source.directURLs[expression.NormalizeName(q.pkgName)] = q.url
 		}
 	}
 
-	return queries, nil
+	return nil
+}
+
+// Install resolves the dependencies and installs the packages. Downloaded
+// files are removed afterward.
+// It returns an error if the packages can't be resolved, or
+// ferror.InstallationRolledBack if any of them fails to install.
+func (i *Installer) Install() error {
+	err := i.process()
+	if cleanupErr := i.source.cleanup(); err == nil {
+		err = cleanupErr
+	}
+
+	return err
+}
+
+func NewInstaller(opt *Options) *Installer {
+	source := newIndexSource()
+	source.findLinks = opt.FindLinks
+	source.offline = opt.Offline
+	source.indexes = append([]string{opt.IndexURL}, opt.ExtraIndexURLs...)
+	for pkgName, indexURL := range opt.PackageIndexes {
+		source.packageIndexes[expression.NormalizeName(pkgName)] = indexURL
+	}
+
+	return &Installer{
+		source:    source,
+		requested: map[string]bool{},
+		opt:       opt,
+	}
 }
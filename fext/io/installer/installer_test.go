@@ -0,0 +1,191 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// setupSchemePaths replaces the installation scheme paths outside
+// config.PythonLibPath with temporary directories. The directory for headers
+// doesn't exist, as it happens before the first package with headers is
+// installed.
+func setupSchemePaths(t *testing.T) (string, string, string) {
+	scriptsPath, headersPath, dataPath := config.ScriptsPath, config.HeadersPath, config.DataPath
+	t.Cleanup(func() {
+		config.ScriptsPath, config.HeadersPath, config.DataPath = scriptsPath, headersPath, dataPath
+	})
+
+	config.ScriptsPath = t.TempDir()
+	config.DataPath = t.TempDir()
+	config.HeadersPath = filepath.Join(config.DataPath, "include", "python3")
+
+	return config.ScriptsPath, config.HeadersPath, config.DataPath
+}
+
+// stageTargets prepares the staged package, then moves it and its targets
+// into place.
+func stageTargets(t *testing.T, tx *transaction, staged string) {
+	targets, err := prepareStaged(tx, staged, nil)
+	assert.Nil(t, err)
+	for _, target := range targets {
+		assert.Nil(t, tx.move(target.dir, target.dst))
+	}
+	assert.Nil(t, tx.move(staged, config.PythonLibPath))
+}
+
+func TestPrepareStaged_Scripts(t *testing.T) {
+	libPath := setupLibPath(t)
+	scriptsPath, _, _ := setupSchemePaths(t)
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+
+	staged := tx.newStagingPath()
+	writeFiles(t, staged, map[string]string{
+		"tool/__init__.py":            "",
+		"tool-1.0.dist-info/METADATA": "Name: tool\nVersion: 1.0\n",
+		"tool-1.0.dist-info/RECORD":   "",
+		"tool-1.0.dist-info/entry_points.txt": "[console_scripts]\ntool = tool:main\n" +
+			"[gui_scripts]\ntool-gui = tool.gui:App.run\n[other]\nplugin = tool.plugin\n",
+	})
+
+	stageTargets(t, tx, staged)
+	assert.Nil(t, tx.commit())
+
+	p, err := pkg.Load("tool")
+	assert.Nil(t, err)
+	files, err := p.GetFiles()
+	assert.Nil(t, err)
+
+	executables, err := p.GetExecutableFiles()
+	assert.Nil(t, err)
+	assert.False(t, executables[filepath.Join(libPath, "tool", "__init__.py")])
+
+	launchers := readFiles(t, scriptsPath)
+	assert.NotEmpty(t, launchers)
+	for name, content := range launchers {
+		assert.Contains(t, files, filepath.Join(scriptsPath, name))
+		assert.Contains(t, content, "import")
+		if runtime.GOOS != "windows" {
+			assert.True(t, executables[filepath.Join(scriptsPath, name)], name)
+		}
+	}
+
+	assert.Nil(t, p.Uninstall())
+	assert.Empty(t, readFiles(t, scriptsPath))
+	_, err = os.Stat(filepath.Join(libPath, "tool"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPrepareStaged_NoScripts(t *testing.T) {
+	setupLibPath(t)
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+	defer tx.rollback()
+
+	staged := tx.newStagingPath()
+	writeFiles(t, staged, map[string]string{
+		"lib/__init__.py":            "",
+		"lib-1.0.dist-info/METADATA": "Name: lib\nVersion: 1.0\n",
+	})
+
+	targets, err := prepareStaged(tx, staged, map[string][]byte{pkg.RequestedFileName: nil})
+	assert.Nil(t, err)
+	assert.Empty(t, targets)
+	assert.FileExists(t, filepath.Join(staged, "lib-1.0.dist-info", "REQUESTED"))
+}
+
+func TestPrepareStaged_DataDirectory(t *testing.T) {
+	libPath := setupLibPath(t)
+	scriptsPath, headersPath, dataPath := setupSchemePaths(t)
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+
+	staged := tx.newStagingPath()
+	writeFiles(t, staged, map[string]string{
+		"ext/__init__.py":                         "",
+		"ext-1.0.dist-info/METADATA":              "Name: ext\nVersion: 1.0\n",
+		"ext-1.0.data/purelib/ext/pure.py":        "pure",
+		"ext-1.0.data/platlib/ext_native.so":      "native",
+		"ext-1.0.data/scripts/ext-run":            "#!python\nprint()\n",
+		"ext-1.0.data/scripts/ext-sh":             "#!/bin/sh\n",
+		"ext-1.0.data/headers/ext.h":              "header",
+		"ext-1.0.data/data/share/ext/config.json": "{}",
+	})
+
+	stageTargets(t, tx, staged)
+	assert.Nil(t, tx.commit())
+
+	// Only the scripts are executable
+	scriptsRel, err := filepath.Rel(libPath, scriptsPath)
+	assert.Nil(t, err)
+	scriptsRel = filepath.ToSlash(scriptsRel)
+	assert.Equal(t, map[string]string{
+		"a/__init__.py":                                "old",
+		"a-1.0.dist-info/RECORD":                       "old",
+		"ns/b/__init__.py":                             "other",
+		"ext/__init__.py":                              "",
+		"ext/pure.py":                                  "pure",
+		"ext_native.so":                                "native",
+		"ext-1.0.dist-info/INSTALLER":                  "fext\n",
+		"ext-1.0.dist-info/METADATA":                   "Name: ext\nVersion: 1.0\n",
+		"ext-1.0.dist-info/" + pkg.ExecutablesFileName: scriptsRel + "/ext-run\n" + scriptsRel + "/ext-sh\n",
+	}, withoutRecord(readFiles(t, libPath)))
+
+	scripts := readFiles(t, scriptsPath)
+	assert.Equal(t, "#!/bin/sh\n", scripts["ext-sh"])
+	assert.NotContains(t, scripts["ext-run"], "#!python\n")
+	assert.True(t, strings.HasSuffix(scripts["ext-run"], "\nprint()\n"))
+	for name := range scripts {
+		info, err := os.Stat(filepath.Join(scriptsPath, name))
+		assert.Nil(t, err)
+		assert.NotZero(t, info.Mode().Perm()&0100, name)
+	}
+
+	assert.Equal(t, map[string]string{"ext.h": "header"}, readFiles(t, filepath.Join(headersPath, "ext")))
+	assert.Equal(t, "{}", readFiles(t, dataPath)["share/ext/config.json"])
+
+	p, err := pkg.Load("ext")
+	assert.Nil(t, err)
+	assert.Nil(t, p.Uninstall())
+	assert.Empty(t, readFiles(t, scriptsPath))
+	assert.NotContains(t, readFiles(t, dataPath), "share/ext/config.json")
+	assert.NotContains(t, readFiles(t, dataPath), "include/python3/ext/ext.h")
+}
+
+func TestPrepareStaged_UnexpectedScheme(t *testing.T) {
+	setupLibPath(t)
+	setupSchemePaths(t)
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+	defer tx.rollback()
+
+	staged := tx.newStagingPath()
+	writeFiles(t, staged, map[string]string{
+		"ext-1.0.dist-info/METADATA": "Name: ext\nVersion: 1.0\n",
+		"ext-1.0.data/etc/ext.conf":  "",
+	})
+
+	_, err = prepareStaged(tx, staged, nil)
+	assert.Equal(t, &ferror.UnexpectedScheme{Scheme: "etc"}, err)
+}
+
+// withoutRecord removes the RECORD files from the listed files, since their
+// content depends on the paths of the test directories.
+func withoutRecord(files map[string]string) map[string]string {
+	for name := range files {
+		if strings.HasSuffix(name, "/"+pkg.RecordFileName) && !strings.HasPrefix(name, "a-") {
+			delete(files, name)
+		}
+	}
+
+	return files
+}
@@ -0,0 +1,312 @@
+package installer
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+)
+
+// LockFileName is the default name of the lock file.
+const LockFileName = "fext.lock"
+
+// lockFormatVersion is the version of the lock file format. It is increased on
+// incompatible changes only.
+const lockFormatVersion = 1
+
+// lockFile is the content of the lock file. It contains the exact
+// distribution files of all packages needed to satisfy the requirements.
+// Packages are sorted by name, so the same resolution always produces the same
+// file.
+//
+// The lock file is specific to the environment it is created in. The markers
+// are evaluated for the current interpreter and platform during the
+// resolution, so the packages required only on other systems are not listed,
+// and the selected files may be incompatible with them. The markers written
+// to the file only skip the packages that are not required in another
+// environment.
+type lockFile struct {
+	Version      int             `json:"version"`
+	Requirements []string        `json:"requirements"`
+	Packages     []lockedPackage `json:"packages"`
+}
+
+// lockedPackage is a package pinned in the lock file.
+type lockedPackage struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	FileName string `json:"filename"`
+	URL      string `json:"url"`
+	SHA256   string `json:"sha256"`
+	// Python markers (PEP 508) under which the package is required. It is
+	// empty if the package is always required
+	Markers string `json:"markers,omitempty"`
+}
+
+// readLockFile reads and decodes the lock file.
+// Returns ferror.UnsupportedLockFile if the format version is unknown.
+func readLockFile(fileName string) (*lockFile, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var l lockFile
+	if err = json.Unmarshal(data, &l); err != nil {
+		return nil, err
+	} else if l.Version != lockFormatVersion {
+		return nil, &ferror.UnsupportedLockFile{Version: strconv.Itoa(l.Version)}
+	}
+
+	return &l, nil
+}
+
+// write encodes the lock file and writes it to the specified path.
+func (l *lockFile) write(fileName string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, append(data, '\n'), 0644)
+}
+
+// joinMarkers joins the non-empty marker expressions with the logical
+// operator. Each expression is wrapped in parentheses if there is more than
+// one of them.
+func joinMarkers(operator string, markers ...string) string {
+	var expressions []string
+	for _, m := range markers {
+		if m != "" {
+			expressions = append(expressions, m)
+		}
+	}
+
+	if len(expressions) < 2 {
+		return strings.Join(expressions, "")
+	}
+
+	return "(" + strings.Join(expressions, ") "+operator+" (") + ")"
+}
+
+// combineMarkers computes the markers under which each pinned package is
+// required. The package is required if any of its constraints is put forward,
+// i.e. both the constraint markers and the markers of its origin are true.
+// Packages requested by the user are always required.
+func combineMarkers(s *resolution) map[string]string {
+	markers := map[string]string{}
+
+	for _, pkgName := range s.order {
+		var alternatives []string
+		var always bool
+
+		for _, c := range s.constraints[pkgName] {
+			m := c.markers
+			if c.origin != nil {
+				// The origin is pinned before its dependencies, so its markers
+				// are already known
+				m = joinMarkers("and", markers[c.origin.name], m)
+			}
+
+			if m == "" {
+				always = true
+				break
+			} else if !contains(alternatives, m) {
+				alternatives = append(alternatives, m)
+			}
+		}
+
+		if !always {
+			markers[pkgName] = joinMarkers("or", alternatives...)
+		}
+	}
+
+	return markers
+}
+
+// getSHA256 returns the SHA-256 digest of the candidate distribution file.
+// The digest provided by the repository is used if possible, otherwise the
+// file is downloaded to compute it.
+func (i *Installer) getSHA256(c *candidate) (string, error) {
+	if algorithm, digest, found := strings.Cut(c.remote.Hash, ":"); found && algorithm == "sha256" {
+		return digest, nil
+	}
+
+	if err := i.source.download(c); err != nil {
+		return "", err
+	}
+
+	return web.ComputeDigest(c.filePath, "sha256")
+}
+
+// lock resolves the requested packages for the current environment without
+// taking the installed ones into account and writes the lock file. The
+// credentials are removed from the file URLs.
+// It returns an error if the resolution fails or the file can't be written.
+func (i *Installer) lock(fileName string) error {
+	if err := i.prepareHashes(); err != nil {
+		return err
+	}
+
+	i.source.ignoreInstalled = true
+	s, err := newResolver(i.source, i.opt.NoDependencies).resolveQueries(i.queries)
+	if err != nil {
+		return err
+	}
+
+	l := &lockFile{
+		Version:      lockFormatVersion,
+		Requirements: i.requirements,
+		Packages:     []lockedPackage{},
+	}
+	markers := combineMarkers(s)
+
+	for _, pkgName := range s.order {
+		c := s.pins[pkgName]
+		warnYanked(c)
+		digest, err := i.getSHA256(c)
+		if err != nil {
+			return err
+		}
+
+		// The links of the authenticated index embed its credentials
+		url := web.StripCredentials(web.StripFragment(c.remote.Link))
+		l.Packages = append(l.Packages, lockedPackage{
+			Name:     pkgName,
+			Version:  c.version,
+			FileName: c.remote.FileName,
+			URL:      url,
+			SHA256:   digest,
+			Markers:  markers[pkgName],
+		})
+	}
+
+	sort.Slice(l.Packages, func(a, b int) bool {
+		return l.Packages[a].Name < l.Packages[b].Name
+	})
+
+	return l.write(fileName)
+}
+
+// Lock resolves the dependencies of the requested packages and writes the
+// exact versions and distribution files to the lock file. The lock file is
+// valid for the current environment only. Downloaded files are removed
+// afterward.
+// It returns an error if the packages can't be resolved.
+func (i *Installer) Lock(fileName string) error {
+	err := i.lock(fileName)
+	if cleanupErr := i.source.cleanup(); err == nil {
+		err = cleanupErr
+	}
+
+	return err
+}
+
+// isLockedRequired checks if the locked package is required in the current
+// environment according to its markers, and is not installed in the locked
+// version yet.
+func (i *Installer) isLockedRequired(pkgName, version, markers string) (bool, error) {
+	if markers != "" {
+		required, err := expression.CompareMarkers(markers)
+		if err != nil || !required {
+			return false, err
+		}
+	}
+
+	installed, err := i.source.getInstalled(pkgName)
+	if err != nil {
+		return false, err
+	}
+
+	return installed == nil || installed.Version != version, nil
+}
+
+// getFextLockCandidates reads the lock file and returns the candidates of the
+// packages that are required in the current environment and not installed
+// yet. Distribution files are pinned to their SHA-256 digests, and are
+// downloaded with the credentials of the index located on the same host. The
+// packages listed in the lock file requirements are marked requested.
+func (i *Installer) getFextLockCandidates(fileName string) ([]*candidate, error) {
+	l, err := readLockFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, requirement := range l.Requirements {
+		q, err := newRawQuery(requirement, false)
+		if err != nil {
+			return nil, err
+		}
+		i.requested[expression.NormalizeName(q.pkgName)] = true
+	}
+
+	var candidates []*candidate
+	for _, p := range l.Packages {
+		required, err := i.isLockedRequired(p.Name, p.Version, p.Markers)
+		if err != nil {
+			return nil, err
+		} else if !required {
+			continue
+		}
+
+		candidates = append(candidates, &candidate{
+			name:    expression.NormalizeName(p.Name),
+			version: p.Version,
+			remote: &web.Candidate{
+				FileName: p.FileName,
+				Version:  p.Version,
+				Link:     web.AddCredentials(p.URL, i.source.getAllIndexes()) + "#sha256=" + p.SHA256,
+				Hash:     "sha256:" + p.SHA256,
+			},
+		})
+	}
+
+	return candidates, nil
+}
+
+// getLockedCandidates reads the lock files and returns the candidates to be
+// installed. Files named according to PEP 751 are read as "pylock.toml", the
+// others as "fext.lock".
+func (i *Installer) getLockedCandidates(fileNames []string) ([]*candidate, error) {
+	var candidates []*candidate
+
+	for _, fileName := range fileNames {
+		var locked []*candidate
+		var err error
+		if isPyLockFile(fileName) {
+			locked, err = i.getPyLockCandidates(fileName)
+		} else {
+			locked, err = i.getFextLockCandidates(fileName)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, locked...)
+	}
+
+	return candidates, nil
+}
+
+// InstallLocked installs exactly the distribution files pinned in the lock
+// files, either "fext.lock" or "pylock.toml" (PEP 751), without resolving the
+// dependencies. Packages already installed in the
+// pinned versions are skipped. Downloaded files are removed afterward.
+// It returns an error if the lock files can't be read.
+func (i *Installer) InstallLocked(fileNames []string) error {
+	candidates, err := i.getLockedCandidates(fileNames)
+	if err == nil {
+		err = i.apply(candidates)
+	}
+
+	if cleanupErr := i.source.cleanup(); err == nil {
+		err = cleanupErr
+	}
+
+	return err
+}
@@ -0,0 +1,86 @@
+package installer
+
+import (
+	"sync"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// maxLookups is the number of the packages looked up in the indexes at once.
+const maxLookups = 8
+
+// RemoteStatus describes the installed package as it is seen by the indexes.
+type RemoteStatus struct {
+	// Installed package
+	Package *pkg.Package
+	// The newest version available in the indexes, the yanked versions are
+	// not taken into account. It is empty if there is no such version
+	Latest string
+	// Determines if the installed version has been yanked since (PEP 592)
+	Yanked bool
+	// Reason of yanking, it may be empty even if the version is yanked
+	YankedReason string
+}
+
+// IsOutdated checks if a newer version of the package is available.
+func (s *RemoteStatus) IsOutdated() bool {
+	newer, _ := expression.CompareVersion(s.Latest, ">", s.Package.Version)
+	return s.Latest != "" && newer
+}
+
+// GetRemoteStatus looks up the installed package in the indexes. The status
+// of the package installed from the direct reference is empty, since it
+// doesn't come from the indexes, as well as the status of the package that
+// can't be found.
+// Returns an error if the indexes can't be reached.
+func (i *Installer) GetRemoteStatus(p *pkg.Package) (*RemoteStatus, error) {
+	status := &RemoteStatus{Package: p}
+	if directURL, err := p.GetDirectURL(); err != nil {
+		return nil, err
+	} else if directURL != nil {
+		return status, nil
+	}
+
+	remote, err := i.source.getRemoteCandidates(expression.NormalizeName(p.Name))
+	if isNotFound(err) {
+		return status, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Candidates are sorted from the newest version to the oldest one
+	for _, c := range remote {
+		if status.Latest == "" && !c.Yanked {
+			status.Latest = c.Version
+		}
+		if equal, _ := expression.CompareVersion(c.Version, "==", p.Version); equal {
+			status.Yanked, status.YankedReason = c.Yanked, c.YankedReason
+		}
+	}
+
+	return status, nil
+}
+
+// GetRemoteStatuses looks up the installed packages in the indexes, several
+// of them at once. The statuses and the lookup errors are returned in the
+// order of the packages, the status is nil if its lookup has failed.
+func (i *Installer) GetRemoteStatuses(packages []*pkg.Package) ([]*RemoteStatus, []error) {
+	statuses := make([]*RemoteStatus, len(packages))
+	errs := make([]error, len(packages))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxLookups)
+	for n, p := range packages {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(n int, p *pkg.Package) {
+			defer wg.Done()
+			statuses[n], errs[n] = i.GetRemoteStatus(p)
+			<-semaphore
+		}(n, p)
+	}
+	wg.Wait()
+
+	return statuses, errs
+}
@@ -0,0 +1,395 @@
+package installer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+	fextio "github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/io/web"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// pyLockVersion is the version of the PEP 751 lock file format written by
+// fext. Files of any 1.x version can be read.
+const pyLockVersion = "1.0"
+
+// pyLockCreator is the name of the tool recorded in the lock files written by
+// fext.
+const pyLockCreator = "fext"
+
+// pyLock is the content of the lock file in the PEP 751 format
+// (https://peps.python.org/pep-0751/). Only the fields used by fext are
+// described, the others are ignored.
+type pyLock struct {
+	LockVersion      string          `toml:"lock-version"`
+	Environments     []string        `toml:"environments,omitempty"`
+	RequiresPython   string          `toml:"requires-python,omitempty"`
+	Extras           []string        `toml:"extras"`
+	DependencyGroups []string        `toml:"dependency-groups"`
+	DefaultGroups    []string        `toml:"default-groups"`
+	CreatedBy        string          `toml:"created-by"`
+	Packages         []pyLockPackage `toml:"packages"`
+}
+
+// pyLockPackage is a package listed in the PEP 751 lock file.
+type pyLockPackage struct {
+	Name    string `toml:"name"`
+	Version string `toml:"version,omitempty"`
+	// Python markers (PEP 508) under which the package is required
+	Marker string `toml:"marker,omitempty"`
+	Index  string `toml:"index,omitempty"`
+	// Packages of the lock file required by this package
+	Dependencies []pyLockDependency `toml:"dependencies,omitempty"`
+	Wheels       []pyLockWheel      `toml:"wheels,omitempty"`
+}
+
+// pyLockDependency is a reference to another package of the PEP 751 lock file.
+// Only the name is used, the other fields are ignored.
+type pyLockDependency struct {
+	Name string `toml:"name"`
+}
+
+// pyLockWheel is a wheel of the package listed in the PEP 751 lock file. It is
+// located either by the URL, or by the path relative to the lock file.
+type pyLockWheel struct {
+	Name string `toml:"name,omitempty"`
+	URL  string `toml:"url,omitempty"`
+	Path string `toml:"path,omitempty"`
+	// Digests of the file, by the hash algorithm name
+	Hashes map[string]string `toml:"hashes"`
+}
+
+// fileName returns the name of the wheel file. If the name is not specified,
+// it is taken from the URL or the path.
+func (w *pyLockWheel) fileName() string {
+	if w.Name != "" {
+		return w.Name
+	} else if w.URL != "" {
+		u, _, _ := strings.Cut(w.URL, "#")
+		return u[strings.LastIndex(u, "/")+1:]
+	}
+
+	return filepath.Base(w.Path)
+}
+
+// getHashes returns the digests of the wheel in the pip format, e.g.
+// "sha256:<digest>". Digests computed with unsupported algorithms are skipped.
+// Returns ferror.MissingHashes if there are no digests at all, or
+// ferror.UnsupportedHash if none of the algorithms are supported.
+func (w *pyLockWheel) getHashes(pkgName string) ([]string, error) {
+	if len(w.Hashes) == 0 {
+		return nil, &ferror.MissingHashes{Package: pkgName}
+	}
+
+	var hashes []string
+	var err error
+	for algorithm, digest := range w.Hashes {
+		s := algorithm + ":" + digest
+		if _, _, err = web.ParseHash(s); err == nil {
+			hashes = append(hashes, s)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, err
+	}
+	sort.Strings(hashes)
+
+	return hashes, nil
+}
+
+// isPyLockFile checks if the file name follows the PEP 751 naming convention,
+// i.e. "pylock.toml" or "pylock.<name>.toml".
+func isPyLockFile(fileName string) bool {
+	name := filepath.Base(fileName)
+	return name == "pylock.toml" || strings.HasPrefix(name, "pylock.") && strings.HasSuffix(name, ".toml")
+}
+
+// readPyLock reads and decodes the PEP 751 lock file.
+// Returns ferror.UnsupportedLockFile if the major format version is unknown,
+// or ferror.IncompatibleEnvironment if the file is intended for other systems.
+func readPyLock(fileName string) (*pyLock, error) {
+	var l pyLock
+	if _, err := toml.DecodeFile(fileName, &l); err != nil {
+		return nil, err
+	}
+
+	if major, _, _ := strings.Cut(l.LockVersion, "."); major != "1" {
+		return nil, &ferror.UnsupportedLockFile{Version: l.LockVersion}
+	}
+
+	if len(l.Environments) == 0 {
+		return &l, nil
+	}
+	for _, env := range l.Environments {
+		compatible, err := expression.CompareMarkers(env)
+		if err != nil {
+			return nil, err
+		} else if compatible {
+			return &l, nil
+		}
+	}
+
+	return nil, ferror.IncompatibleEnvironment
+}
+
+// getTopLevelNames returns the normalized names of the packages that are not
+// required by any other package of the lock file. If the lock file doesn't
+// describe the dependencies, all the packages are top-level.
+func (l *pyLock) getTopLevelNames() map[string]bool {
+	names := map[string]bool{}
+	for _, p := range l.Packages {
+		names[expression.NormalizeName(p.Name)] = true
+	}
+	for _, p := range l.Packages {
+		for _, dep := range p.Dependencies {
+			if name := expression.NormalizeName(dep.Name); name != expression.NormalizeName(p.Name) {
+				delete(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// getPyLockCandidates reads the PEP 751 lock file and returns the candidates
+// of the packages that are required in the current environment and not
+// installed yet. For each package, the first wheel compatible with the system
+// is selected. The hashes of the wheels are passed to the candidate source, so
+// the files are verified after downloading. The file doesn't tell which
+// packages were requested, so the top-level ones are marked requested.
+// Returns ferror.NoCompatibleWheel if none of the package wheels can be
+// installed, e.g. if only the source distribution is listed.
+func (i *Installer) getPyLockCandidates(fileName string) ([]*candidate, error) {
+	l, err := readPyLock(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	topLevel := l.getTopLevelNames()
+	var candidates []*candidate
+	for _, p := range l.Packages {
+		required, err := i.isLockedRequired(p.Name, p.Version, p.Marker)
+		if err != nil {
+			return nil, err
+		} else if !required {
+			continue
+		}
+
+		wheel, err := selectPyLockWheel(p)
+		if err != nil {
+			return nil, err
+		}
+
+		name := expression.NormalizeName(p.Name)
+		hashes, err := wheel.getHashes(name)
+		if err != nil {
+			return nil, err
+		}
+		i.source.hashes[name] = hashes
+		if topLevel[name] {
+			i.requested[name] = true
+		}
+
+		c := &candidate{
+			name:    name,
+			version: p.Version,
+			remote: &web.Candidate{
+				FileName: wheel.fileName(),
+				Version:  p.Version,
+				Link:     wheel.URL,
+			},
+		}
+
+		if wheel.URL == "" {
+			// The wheel is stored locally, next to the lock file. It is not
+			// removed after the installation
+			c.filePath = filepath.Join(filepath.Dir(fileName), filepath.FromSlash(wheel.Path))
+			if err = web.VerifyFile(c.filePath, hashes); err != nil {
+				return nil, err
+			}
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates, nil
+}
+
+// selectPyLockWheel returns the wheel of the package that is the most
+// preferred by the system according to its compatibility tags. If several
+// wheels are equally preferred, the first one is selected.
+// Returns ferror.NoCompatibleWheel if there is no compatible wheel.
+func selectPyLockWheel(p pyLockPackage) (*pyLockWheel, error) {
+	var best *pyLockWheel
+	bestPriority := -1
+	for n := range p.Wheels {
+		wheel := &p.Wheels[n]
+		if wheel.URL == "" && wheel.Path == "" {
+			continue
+		}
+
+		priority := web.GetTagPriority(wheel.fileName())
+		if priority != -1 && (best == nil || priority < bestPriority) {
+			best, bestPriority = wheel, priority
+		}
+	}
+
+	if best == nil {
+		return nil, &ferror.NoCompatibleWheel{Package: p.Name}
+	}
+
+	return best, nil
+}
+
+// getInstalledWheel describes the wheel the installed package was installed
+// from, and returns the index it was found in. The wheel of the package
+// installed from the direct reference is taken from "direct_url.json".
+// Otherwise, the wheel of the installed version is looked up in the
+// configured indexes, and is downloaded to compare its RECORD with the
+// installed one. Returns nil if the origin of the package can't be confirmed.
+// Returns an error if the indexes can't be reached or the file can't be read.
+func (i *Installer) getInstalledWheel(p *pkg.Package) (*pyLockWheel, string, error) {
+	directURL, err := p.GetDirectURL()
+	if err != nil {
+		return nil, "", err
+	} else if directURL != nil {
+		if directURL.ArchiveInfo == nil || len(directURL.ArchiveInfo.Hashes) == 0 {
+			return nil, "", nil
+		}
+		url := web.StripCredentials(directURL.URL)
+		return &pyLockWheel{
+			Name:   web.GetLinkFileName(url),
+			URL:    url,
+			Hashes: directURL.ArchiveInfo.Hashes,
+		}, "", nil
+	}
+
+	entries, err := pkg.ReadRecord(p.GetRecordPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	installed := map[string]string{}
+	for _, entry := range entries {
+		installed[entry.Path] = entry.Hash
+	}
+
+	name := expression.NormalizeName(p.Name)
+	conditions := []expression.Condition{{Operator: "==", Value: p.Version}}
+	for _, indexURL := range i.source.getIndexes(name) {
+		candidates, err := web.NewIndexRequest(indexURL, name, conditions).GetCandidates()
+		if isNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		c := candidates[0]
+		filePath, err := web.NewRequest(name, nil).DownloadPackage(c.Link)
+		if err != nil {
+			return nil, "", err
+		}
+		matches, err := fextio.MatchesRecord(filePath, installed)
+		var digest string
+		if err == nil && matches {
+			digest, err = web.ComputeDigest(filePath, "sha256")
+		}
+		_ = os.Remove(filePath)
+		if err != nil {
+			return nil, "", err
+		} else if !matches {
+			continue
+		}
+
+		return &pyLockWheel{
+			Name:   c.FileName,
+			URL:    web.StripCredentials(web.StripFragment(c.Link)),
+			Hashes: map[string]string{"sha256": digest},
+		}, web.StripCredentials(indexURL), nil
+	}
+
+	return nil, "", nil
+}
+
+// lockInstalled describes the installed package for the PEP 751 lock file,
+// along with the wheel it was installed from, see getInstalledWheel. If the
+// wheel can't be found, only the name, the version and the dependencies of
+// the package are described. Only the dependencies listed in the lock file
+// are referenced.
+func (i *Installer) lockInstalled(p *pkg.Package, lockedNames map[string]bool) pyLockPackage {
+	locked := pyLockPackage{
+		Name:    expression.NormalizeName(p.Name),
+		Version: p.Version,
+	}
+
+	// The dependencies that can't be parsed are not referenced, so the
+	// package is taken for a top-level one
+	depNames, _ := p.GetDependencyNames()
+	sort.Strings(depNames)
+	for n, name := range depNames {
+		if lockedNames[name] && name != locked.Name && (n == 0 || depNames[n-1] != name) {
+			locked.Dependencies = append(locked.Dependencies, pyLockDependency{Name: name})
+		}
+	}
+
+	// The lookup errors are not fatal, the package is just not pinned to
+	// the file
+	wheel, indexURL, err := i.getInstalledWheel(p)
+	if err == nil && wheel != nil {
+		locked.Index = indexURL
+		locked.Wheels = []pyLockWheel{*wheel}
+	}
+
+	return locked
+}
+
+// WritePyLock writes the PEP 751 lock file describing the installed packages.
+// Packages are sorted by name, and the wheels of their installed versions are
+// looked up in the configured indexes, several of them at once.
+// Returns an error if the file can't be encoded.
+func (i *Installer) WritePyLock(w io.Writer, packages []*pkg.Package) error {
+	l := pyLock{
+		LockVersion:      pyLockVersion,
+		Extras:           []string{},
+		DependencyGroups: []string{},
+		DefaultGroups:    []string{},
+		CreatedBy:        pyLockCreator,
+		Packages:         make([]pyLockPackage, len(packages)),
+	}
+
+	lockedNames := map[string]bool{}
+	for _, p := range packages {
+		lockedNames[expression.NormalizeName(p.Name)] = true
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxLookups)
+	for n, p := range packages {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(n int, p *pkg.Package) {
+			defer wg.Done()
+			l.Packages[n] = i.lockInstalled(p, lockedNames)
+			<-semaphore
+		}(n, p)
+	}
+	wg.Wait()
+
+	sort.Slice(l.Packages, func(a, b int) bool {
+		return l.Packages[a].Name < l.Packages[b].Name
+	})
+
+	enc := toml.NewEncoder(w)
+	enc.Indent = ""
+	return enc.Encode(l)
+}
@@ -0,0 +1,236 @@
+package installer
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// testPyLock is a lock file in the form written by other tools, with the
+// fields that fext doesn't use
+const testPyLock = `lock-version = "1.0"
+requires-python = ">=3.8"
+environments = ["sys_platform == 'win32'", "sys_platform == 'linux'", "sys_platform == 'darwin'"]
+created-by = "uv"
+
+[[packages]]
+name = "Fext_Test.Local"
+version = "1.0"
+dependencies = [{ name = "fext-test-remote" }]
+wheels = [
+    { path = "wheels/fext_test_local-1.0-py3-none-any.whl", hashes = { md5 = "0", sha256 = "DIGEST" } },
+]
+
+[[packages]]
+name = "fext-test-remote"
+version = "2.0"
+index = "https://pypi.org/simple"
+
+[[packages.wheels]]
+url = "https://example.com/fext_test_remote-2.0-py2-none-any.whl"
+upload-time = 2024-01-01T00:00:00Z
+hashes = { sha256 = "0123" }
+
+[[packages.wheels]]
+url = "https://example.com/fext_test_remote-2.0-py3-none-any.whl"
+size = 1024
+
+[packages.wheels.hashes]
+sha256 = "4567"
+
+[[packages]]
+name = "fext-test-skipped"
+version = "1.0"
+marker = "python_version < '3'"
+sdist = { url = "https://example.com/fext_test_skipped-1.0.tar.gz", hashes = { sha256 = "89ab" } }
+`
+
+func TestIsPyLockFile(t *testing.T) {
+	assert.True(t, isPyLockFile("pylock.toml"))
+	assert.True(t, isPyLockFile(filepath.Join("dir", "pylock.dev.toml")))
+	assert.False(t, isPyLockFile("fext.lock"))
+	assert.False(t, isPyLockFile("pyproject.toml"))
+}
+
+func TestInstaller_getPyLockCandidates(t *testing.T) {
+	dir := t.TempDir()
+	wheel := filepath.Join(dir, "wheels", "fext_test_local-1.0-py3-none-any.whl")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(wheel), 0755))
+	assert.Nil(t, os.WriteFile(wheel, []byte("wheel"), 0644))
+
+	fileName := filepath.Join(dir, "pylock.toml")
+	content := strings.Replace(testPyLock, "DIGEST", "0123", 1)
+	assert.Nil(t, os.WriteFile(fileName, []byte(content), 0644))
+
+	i := NewInstaller(DefaultOptions())
+	_, err := i.getPyLockCandidates(fileName)
+	var mismatch *ferror.HashMismatch
+	assert.ErrorAs(t, err, &mismatch)
+
+	digest, err := web.ComputeDigest(wheel, "sha256")
+	assert.Nil(t, err)
+	content = strings.Replace(testPyLock, "DIGEST", digest, 1)
+	assert.Nil(t, os.WriteFile(fileName, []byte(content), 0644))
+
+	candidates, err := i.getPyLockCandidates(fileName)
+	assert.Nil(t, err)
+	assert.Len(t, candidates, 2)
+
+	assert.Equal(t, "fext-test-local", candidates[0].name)
+	assert.Equal(t, wheel, candidates[0].filePath)
+	assert.Equal(t, []string{"sha256:" + digest}, i.source.hashes["fext-test-local"])
+
+	assert.Equal(t, "fext-test-remote", candidates[1].name)
+	assert.Equal(t, "fext_test_remote-2.0-py3-none-any.whl", candidates[1].remote.FileName)
+	assert.Equal(t, "https://example.com/fext_test_remote-2.0-py3-none-any.whl", candidates[1].remote.Link)
+	assert.Equal(t, []string{"sha256:4567"}, i.source.hashes["fext-test-remote"])
+
+	// Only the packages not required by the others are marked requested
+	assert.Equal(t, map[string]bool{"fext-test-local": true}, i.requested)
+}
+
+func TestReadPyLock(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "pylock.toml")
+
+	assert.Nil(t, os.WriteFile(fileName, []byte(`lock-version = "2.0"`), 0644))
+	_, err := readPyLock(fileName)
+	var unsupported *ferror.UnsupportedLockFile
+	assert.ErrorAs(t, err, &unsupported)
+
+	assert.Nil(t, os.WriteFile(fileName, []byte("lock-version = \"1.0\"\nenvironments = [\"python_version < '3'\"]"), 0644))
+	_, err = readPyLock(fileName)
+	assert.ErrorIs(t, err, ferror.IncompatibleEnvironment)
+
+	_, err = selectPyLockWheel(pyLockPackage{Name: "a"})
+	var noWheel *ferror.NoCompatibleWheel
+	assert.ErrorAs(t, err, &noWheel)
+}
+
+func TestPyLock_Encode(t *testing.T) {
+	l := pyLock{
+		LockVersion:      pyLockVersion,
+		Extras:           []string{},
+		DependencyGroups: []string{},
+		DefaultGroups:    []string{},
+		CreatedBy:        pyLockCreator,
+		Packages: []pyLockPackage{{
+			Name:         "a",
+			Version:      "1.0",
+			Index:        "https://pypi.org/simple",
+			Dependencies: []pyLockDependency{{Name: "b"}},
+			Wheels: []pyLockWheel{{
+				Name:   "a-1.0-py3-none-any.whl",
+				URL:    "https://example.com/a-1.0-py3-none-any.whl",
+				Hashes: map[string]string{"sha256": "0123"},
+			}},
+		}},
+	}
+
+	var b bytes.Buffer
+	assert.Nil(t, toml.NewEncoder(&b).Encode(l))
+
+	var decoded pyLock
+	_, err := toml.Decode(b.String(), &decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, l, decoded)
+}
+
+// newWheelServer serves the Simple API page of the "lib" package and its
+// wheel built from the files.
+func newWheelServer(t *testing.T, files map[string]string) *httptest.Server {
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	entries, err := pkg.ScanRecord(dir, "lib-1.0.dist-info")
+	assert.Nil(t, err)
+	assert.Nil(t, pkg.WriteRecord(dir, "lib-1.0.dist-info", entries))
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range readFiles(t, dir) {
+		f, err := w.Create(name)
+		assert.Nil(t, err)
+		_, err = f.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/simple/lib/":
+			_, _ = w.Write([]byte(`<a href="/files/lib-1.0-py3-none-any.whl">lib-1.0-py3-none-any.whl</a>`))
+		case "/files/lib-1.0-py3-none-any.whl":
+			_, _ = w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestInstaller_getInstalledWheel(t *testing.T) {
+	libPath := setupLibPath(t)
+	downloadPath := config.DownloadPath
+	t.Cleanup(func() { config.DownloadPath = downloadPath })
+	config.DownloadPath = t.TempDir()
+
+	files := map[string]string{
+		"lib/__init__.py":            "private",
+		"lib-1.0.dist-info/METADATA": "Name: lib\nVersion: 1.0\n",
+	}
+	private := newWheelServer(t, files)
+	defer private.Close()
+	public := newWheelServer(t, map[string]string{
+		"lib/__init__.py":            "public",
+		"lib-1.0.dist-info/METADATA": "Name: lib\nVersion: 1.0\n",
+	})
+	defer public.Close()
+
+	writeFiles(t, libPath, files)
+	entries, err := pkg.ScanRecord(libPath, "lib-1.0.dist-info")
+	assert.Nil(t, err)
+	assert.Nil(t, pkg.WriteRecord(libPath, "lib-1.0.dist-info", entries))
+	p, err := pkg.Load("lib")
+	assert.Nil(t, err)
+
+	// The wheel of the same name and version in another index is not taken
+	// for the installed one
+	opt := DefaultOptions()
+	opt.IndexURL = public.URL + "/simple/"
+	wheel, indexURL, err := NewInstaller(opt).getInstalledWheel(p)
+	assert.Nil(t, err)
+	assert.Nil(t, wheel)
+	assert.Empty(t, indexURL)
+
+	opt.ExtraIndexURLs = []string{private.URL + "/simple/"}
+	wheel, indexURL, err = NewInstaller(opt).getInstalledWheel(p)
+	assert.Nil(t, err)
+	assert.NotNil(t, wheel)
+	assert.Equal(t, private.URL+"/simple/", indexURL)
+	assert.Equal(t, private.URL+"/files/lib-1.0-py3-none-any.whl", wheel.URL)
+	assert.Len(t, wheel.Hashes["sha256"], 64)
+
+	// The package installed from the direct reference is described by its URL
+	writeFiles(t, libPath, map[string]string{
+		"lib-1.0.dist-info/" + pkg.DirectURLFileName: `{"url": "https://host/lib-1.0-py3-none-any.whl", "archive_info": {"hashes": {"sha256": "0123"}}}`,
+	})
+	wheel, indexURL, err = NewInstaller(opt).getInstalledWheel(p)
+	assert.Nil(t, err)
+	assert.Empty(t, indexURL)
+	assert.Equal(t, &pyLockWheel{
+		Name:   "lib-1.0-py3-none-any.whl",
+		URL:    "https://host/lib-1.0-py3-none-any.whl",
+		Hashes: map[string]string{"sha256": "0123"},
+	}, wheel)
+}
@@ -1,7 +1,12 @@
 package installer
 
 import (
+	"path/filepath"
+	"strings"
+
 	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
 	"github.com/fextpkg/cli/fext/pkg"
 )
 
@@ -12,46 +17,188 @@ type Query struct {
 	pkgName string
 	// Conditions (operators and versions) to use for searching in the repository
 	conditions []expression.Condition
-	// Duplicate struct in case a search for extra packages was performed
-	// without the required package already installed
-	extraNames *Query
+	// Extra names whose dependencies should be installed along with the package
+	extraNames []string
+	// Allowed hashes of the distribution file in the pip format, e.g.
+	// "sha256:<digest>"
+	hashes []string
+	// Python markers (PEP 508) under which the dependency is required
+	markers string
+	// URL of the distribution file (PEP 508 direct reference). It is empty
+	// if the package is looked up in the index
+	url string
 	// Mark package a dependency of another
 	isDependency bool
 }
 
-// newRawQuery pre-parses conditional statements and creates a new query
-func newRawQuery(s string) *Query {
-	pkgName, conditions := expression.ParseConditions(s)
-	return &Query{
-		pkgName:    pkgName,
-		conditions: conditions,
-	}
+// isPinned checks if the query is pinned to an exact version with "==", as
+// required by the hash-checking mode.
+func (q *Query) isPinned() bool {
+	return len(q.conditions) == 1 &&
+		q.conditions[0].Operator == "==" &&
+		!strings.Contains(q.conditions[0].Value, "*")
 }
 
-// newQuery creates a new query with already known parameters
-func newQuery(pkgName string, conditions []expression.Condition, isDependency bool) *Query {
+// newRawQuery separates options, pre-parses extra names and conditional
+// statements and creates a new query. Returns ferror.SyntaxError if extra
+// names syntax is invalid, or any error returned by splitOptions.
+func newRawQuery(s string, isDependency bool) (*Query, error) {
+	s, hashes, err := splitOptions(s)
+	if err != nil {
+		return nil, err
+	}
+
+	s, link, markers, err := splitDirectReference(s)
+	if err != nil {
+		return nil, err
+	}
+
+	s, extraNames, err := expression.ParseExtraNames(s)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgName, conditions := expression.ParseConditions(s)
 	return &Query{
 		pkgName:      pkgName,
 		conditions:   conditions,
+		extraNames:   extraNames,
+		hashes:       hashes,
+		markers:      markers,
+		url:          link,
 		isDependency: isDependency,
+	}, nil
+}
+
+// isWheelPath checks if the requirement is a path to the local wheel file
+// rather than a package name.
+func isWheelPath(s string) bool {
+	return strings.HasSuffix(strings.ToLower(s), ".whl") && !strings.Contains(s, "@")
+}
+
+// splitDirectReference separates the URL and the markers of the direct
+// reference (PEP 508) from the package name and extra names, e.g.
+//
+//	splitDirectReference("pkg[extra] @ https://host/pkg-1.0-py3-none-any.whl ; python_version >= '3.8'")
+//	=> "pkg[extra]", "https://host/pkg-1.0-py3-none-any.whl", "python_version >= '3.8'", nil
+//
+// A path to the local wheel file is converted to the "file://" URL, and the
+// package name is taken from the file name. The markers of the requirements
+// without the direct reference are separated by ";" as well.
+// Returns ferror.SyntaxError if the URL is missing or the wheel file name is
+// invalid.
+func splitDirectReference(s string) (string, string, string, error) {
+	if isWheelPath(s) {
+		pkgName, _, err := web.ParseWheelFileName(filepath.Base(s))
+		if err != nil {
+			return "", "", "", err
+		}
+
+		link, err := web.NewFileURL(s)
+		return pkgName, link, "", err
 	}
+
+	name, reference, found := strings.Cut(s, "@")
+	if !found {
+		name, markers, _ := strings.Cut(s, ";")
+		return strings.TrimSpace(name), "", strings.TrimSpace(markers), nil
+	}
+
+	// The URL must be separated from the markers by whitespace, since ";" is
+	// a valid URL character
+	fields := strings.Fields(reference)
+	if len(fields) == 0 || strings.TrimSpace(name) == "" {
+		return "", "", "", ferror.SyntaxError
+	}
+
+	link := fields[0]
+	markers := strings.TrimSpace(strings.Join(fields[1:], " "))
+	if markers != "" {
+		if !strings.HasPrefix(markers, ";") {
+			return "", "", "", ferror.SyntaxError
+		}
+		markers = strings.TrimSpace(markers[1:])
+	}
+
+	return strings.TrimSpace(name), link, markers, nil
 }
 
-// copyQuery creates a new query similar to the one passed
-func copyQuery(q *Query) *Query {
-	return &Query{
-		pkgName:    q.pkgName,
-		conditions: q.conditions,
-		extraNames: q.extraNames,
+// joinDirectReferences joins the direct references split into separate
+// arguments by the shell, e.g. ["pkg", "@", "<url>"] becomes
+// ["pkg @ <url>"].
+func joinDirectReferences(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "@" && len(result) > 0 && i+1 < len(args) {
+			result[len(result)-1] += " @ " + args[i+1]
+			i++
+			continue
+		}
+		result = append(result, args[i])
+	}
+
+	return result
+}
+
+// splitOptions separates the options of the requirement line in the pip format
+// from the requirement itself. Only the "--hash" option is supported, and it
+// can be specified several times, e.g.
+//
+//	splitOptions("pkg==1.0 --hash=sha256:<digest1> --hash sha256:<digest2>")
+//	=> "pkg==1.0", ["sha256:<digest1>", "sha256:<digest2>"], nil
+//
+// Returns ferror.UnknownFlag if another option is passed,
+// ferror.MissingOptionValue if the option is empty, or an error returned by
+// web.ParseHash if the hash is invalid.
+func splitOptions(s string) (string, []string, error) {
+	var requirement, hashes []string
+	fields := strings.Fields(s)
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "--") {
+			requirement = append(requirement, field)
+			continue
+		}
+
+		name, value, found := strings.Cut(field[2:], "=")
+		if name != "hash" {
+			return "", nil, &ferror.UnknownFlag{Flag: name}
+		} else if !found && i+1 < len(fields) {
+			// The value is separated by a space
+			i++
+			value = fields[i]
+		}
+
+		if value == "" {
+			return "", nil, &ferror.MissingOptionValue{Opt: name}
+		} else if _, _, err := web.ParseHash(value); err != nil {
+			return "", nil, err
+		}
+		hashes = append(hashes, value)
 	}
+
+	return strings.Join(requirement, " "), hashes, nil
 }
 
-// extraDependenciesToQuery converts the pkg.Dependency list to a Query list
-func extraDependenciesToQuery(extras []pkg.Dependency) []*Query {
-	var q []*Query
-	for _, extra := range extras {
-		q = append(q, newQuery(extra.PackageName, extra.Conditions, true))
+// dependenciesToQueries converts the pkg.Dependency list to a Query list.
+// Returns ferror.SyntaxError if extra names of any dependency are invalid.
+func dependenciesToQueries(deps []pkg.Dependency) ([]*Query, error) {
+	var queries []*Query
+	for _, dep := range deps {
+		pkgName, extraNames, err := expression.ParseExtraNames(dep.PackageName)
+		if err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, &Query{
+			pkgName:      pkgName,
+			conditions:   dep.Conditions,
+			extraNames:   extraNames,
+			markers:      dep.GetMarkers(),
+			isDependency: true,
+		})
 	}
 
-	return q
+	return queries, nil
 }
@@ -0,0 +1,334 @@
+package installer
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// maxResolutionRounds limits the number of candidates the resolver is allowed
+// to try, since backtracking takes exponential time in the worst case.
+const maxResolutionRounds = 1000
+
+// constraint is a set of conditions put forward for a package.
+type constraint struct {
+	conditions []expression.Condition
+	// Python markers (PEP 508) under which the constraint is put forward
+	markers string
+	// Candidate that depends on the package. It is nil if the package was
+	// requested by the user
+	origin *candidate
+}
+
+// resolution is the state of the dependency resolution. It is copied every
+// time a candidate is pinned, so that the resolver can return to the previous
+// state in case of conflict.
+type resolution struct {
+	// Pinned candidates by the package name
+	pins map[string]*candidate
+	// Package names in order of pinning
+	order []string
+	// Constraints put forward for the packages
+	constraints map[string][]constraint
+	// Requested extra names of the packages
+	extras map[string][]string
+	// Package names waiting for a candidate to be pinned
+	pending []string
+}
+
+// clone creates a deep copy of the resolution state.
+func (s *resolution) clone() *resolution {
+	next := &resolution{
+		pins:        make(map[string]*candidate, len(s.pins)),
+		order:       append([]string(nil), s.order...),
+		constraints: make(map[string][]constraint, len(s.constraints)),
+		extras:      make(map[string][]string, len(s.extras)),
+		pending:     append([]string(nil), s.pending...),
+	}
+	for k, v := range s.pins {
+		next.pins[k] = v
+	}
+	for k, v := range s.constraints {
+		next.constraints[k] = append([]constraint(nil), v...)
+	}
+	for k, v := range s.extras {
+		next.extras[k] = append([]string(nil), v...)
+	}
+
+	return next
+}
+
+// addCauses marks the packages whose candidates put forward the constraints
+// for the package, along with the packages that brought them into the
+// resolution.
+func (s *resolution) addCauses(pkgName string, causes map[string]bool) {
+	for _, c := range s.constraints[pkgName] {
+		if c.origin != nil && !causes[c.origin.name] {
+			causes[c.origin.name] = true
+			s.addCauses(c.origin.name, causes)
+		}
+	}
+}
+
+// resolver searches for a set of package versions that satisfies all the
+// constraints. It pins candidates one by one and backtracks if a conflict
+// occurs.
+type resolver struct {
+	source candidateSource
+	// Do not resolve package dependencies, only extra dependencies
+	noDependencies bool
+	// Number of candidates tried
+	rounds int
+}
+
+// satisfies checks the compliance of the version with all the constraints.
+// Versions that can't be parsed are considered unsuitable.
+func satisfies(version string, constraints []constraint) (bool, error) {
+	for _, c := range constraints {
+		ok, err := expression.CompareConditions(version, c.conditions)
+		if err != nil {
+			if errors.Is(err, strconv.ErrSyntax) {
+				// Post and dev releases can't be compared yet, see the
+				// web.PyPiRequest for details
+				return false, nil
+			}
+			return false, err
+		} else if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// pinsExactly checks if any constraint pins the package to the version with
+// "==" or "===", without a wildcard.
+func pinsExactly(version string, constraints []constraint) bool {
+	for _, c := range constraints {
+		for _, cond := range c.conditions {
+			if cond.Operator != "===" && (cond.Operator != "==" || strings.Contains(cond.Value, "*")) {
+				continue
+			}
+			if ok, _ := expression.CompareVersion(version, cond.Operator, cond.Value); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// withoutYanked removes the yanked candidates, unless the constraints pin the
+// package to their versions exactly (PEP 592).
+func withoutYanked(candidates []*candidate, constraints []constraint) []*candidate {
+	var selectable []*candidate
+	for _, c := range candidates {
+		if !c.isYanked() || pinsExactly(c.version, constraints) {
+			selectable = append(selectable, c)
+		}
+	}
+
+	return selectable
+}
+
+// contains checks if the item exists in the list.
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getDependencies returns the package dependencies along with the
+// dependencies of the requested extra names.
+// Returns ferror.MissingExtra if extra name not found.
+func (r *resolver) getDependencies(p *pkg.Package, extraNames []string) ([]*Query, error) {
+	var deps []pkg.Dependency
+	var err error
+	if !r.noDependencies {
+		deps, err = p.GetDependencies()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, extraName := range extraNames {
+		extraDeps, err := p.GetExtraDependencies(extraName)
+		if err != nil {
+			return nil, err
+		} else if !p.HasExtraName(extraName) {
+			return nil, &ferror.MissingExtra{Name: extraName}
+		}
+		deps = append(deps, extraDeps...)
+	}
+
+	return dependenciesToQueries(deps)
+}
+
+// supply adds the queries to the resolution. The conditions of each query are
+// merged with the constraints already put forward for the package. If the
+// package is already pinned, the pinned version is checked against the new
+// conditions, and the dependencies of newly requested extra names are supplied
+// as well.
+// Returns a conflict if the pinned version doesn't satisfy the conditions.
+func (r *resolver) supply(s *resolution, queries []*Query, origin *candidate) error {
+	for _, q := range queries {
+		pkgName := expression.NormalizeName(q.pkgName)
+		s.constraints[pkgName] = append(s.constraints[pkgName], constraint{
+			conditions: q.conditions,
+			markers:    q.markers,
+			origin:     origin,
+		})
+
+		var extraNames []string
+		for _, extraName := range q.extraNames {
+			if !contains(s.extras[pkgName], extraName) {
+				s.extras[pkgName] = append(s.extras[pkgName], extraName)
+				extraNames = append(extraNames, extraName)
+			}
+		}
+
+		pinned, ok := s.pins[pkgName]
+		if !ok {
+			if !contains(s.pending, pkgName) {
+				s.pending = append(s.pending, pkgName)
+			}
+			continue
+		}
+
+		compatible, err := satisfies(pinned.version, s.constraints[pkgName])
+		if err != nil {
+			return err
+		} else if !compatible {
+			causes := map[string]bool{pkgName: true}
+			s.addCauses(pkgName, causes)
+			return s.newConflict(pkgName, causes, nil)
+		}
+
+		if len(extraNames) > 0 {
+			p, err := r.source.getMetadata(pinned)
+			if err != nil {
+				return err
+			}
+
+			deps, err := r.getDependencies(p, extraNames)
+			if err != nil {
+				return err
+			}
+			if err = r.supply(s, deps, pinned); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolve pins a candidate for the first pending package and continues with
+// the rest of them. Candidates are tried in order of preference until one of
+// them leads to a complete resolution.
+// Returns the final state of the resolution, or a conflict if there is no
+// suitable candidate. Any other error means that the resolution can't be
+// continued.
+func (r *resolver) resolve(s *resolution) (*resolution, error) {
+	if len(s.pending) == 0 {
+		return s, nil
+	}
+	pkgName := s.pending[0]
+
+	var rejected []*conflict
+	causes := map[string]bool{}
+	s.addCauses(pkgName, causes)
+
+	candidates, err := r.source.getCandidates(pkgName)
+	if err != nil && !errors.Is(err, ferror.NoSuitableVersion) {
+		return nil, err
+	}
+	candidates = withoutYanked(candidates, s.constraints[pkgName])
+
+	for _, c := range candidates {
+		compatible, err := satisfies(c.version, s.constraints[pkgName])
+		if err != nil {
+			return nil, err
+		} else if !compatible {
+			continue
+		}
+
+		r.rounds++
+		if r.rounds > maxResolutionRounds {
+			return nil, ferror.ResolutionTooDeep
+		}
+
+		p, err := r.source.getMetadata(c)
+		if err != nil {
+			return nil, err
+		}
+
+		next := s.clone()
+		next.pending = next.pending[1:]
+		next.pins[pkgName] = c
+		next.order = append(next.order, pkgName)
+
+		deps, err := r.getDependencies(p, next.extras[pkgName])
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.supply(next, deps, c)
+		if err == nil {
+			var result *resolution
+			if result, err = r.resolve(next); err == nil {
+				return result, nil
+			}
+		}
+
+		var cf *conflict
+		if !errors.As(err, &cf) {
+			return nil, err
+		} else if !cf.causes[pkgName] {
+			// The conflict doesn't depend on the candidate of this package,
+			// so there is no point in trying the others
+			return nil, cf
+		}
+
+		for name := range cf.causes {
+			if name != pkgName {
+				causes[name] = true
+			}
+		}
+		rejected = append(rejected, cf)
+	}
+
+	cf := s.newConflict(pkgName, causes, candidates)
+	cf.rejected = rejected
+	return nil, cf
+}
+
+// resolveQueries searches for a set of candidates that satisfies the queries
+// and all their dependencies.
+func (r *resolver) resolveQueries(queries []*Query) (*resolution, error) {
+	s := &resolution{
+		pins:        map[string]*candidate{},
+		constraints: map[string][]constraint{},
+		extras:      map[string][]string{},
+	}
+	if err := r.supply(s, queries, nil); err != nil {
+		return nil, err
+	}
+
+	return r.resolve(s)
+}
+
+func newResolver(source candidateSource, noDependencies bool) *resolver {
+	return &resolver{
+		source:         source,
+		noDependencies: noDependencies,
+	}
+}
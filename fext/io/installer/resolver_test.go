@@ -0,0 +1,219 @@
+package installer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// memorySource is a candidate source that serves packages from memory.
+// Each release is described by its version and a list of "Requires-Dist"
+// values.
+type memorySource struct {
+	releases map[string][]memoryRelease
+	// Releases yanked from the index, e.g. "a 1.0"
+	yanked []string
+	// Number of requested metadata
+	requests int
+}
+
+type memoryRelease struct {
+	version  string
+	requires []string
+}
+
+func (s *memorySource) getCandidates(pkgName string) ([]*candidate, error) {
+	var candidates []*candidate
+	for _, release := range s.releases[pkgName] {
+		c := &candidate{name: pkgName, version: release.version}
+		if contains(s.yanked, c.String()) {
+			c.remote = &web.Candidate{Version: c.version, Yanked: true}
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return nil, ferror.NoSuitableVersion
+	}
+
+	return candidates, nil
+}
+
+func (s *memorySource) getMetadata(c *candidate) (*pkg.Package, error) {
+	s.requests++
+	for _, release := range s.releases[c.name] {
+		if release.version != c.version {
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString("Metadata-Version: 2.1\nName: " + c.name + "\nVersion: " + c.version + "\n")
+		for _, req := range release.requires {
+			b.WriteString("Requires-Dist: " + req + "\n")
+			if _, extra, found := strings.Cut(req, "extra == '"); found {
+				b.WriteString("Provides-Extra: " + strings.TrimSuffix(extra, "'") + "\n")
+			}
+		}
+
+		return pkg.LoadFromMetadata([]byte(b.String())), nil
+	}
+
+	return nil, ferror.PackageDirectoryMissing
+}
+
+func resolveNames(source candidateSource, names ...string) (map[string]string, error) {
+	var queries []*Query
+	for _, name := range names {
+		q, err := newRawQuery(name, false)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	s, err := newResolver(source, false).resolveQueries(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	pins := map[string]string{}
+	for name, c := range s.pins {
+		pins[name] = c.version
+	}
+
+	return pins, nil
+}
+
+func TestResolver_Newest(t *testing.T) {
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a": {{"2.0", []string{"b (>=1)"}}, {"1.0", nil}},
+		"b": {{"1.5", nil}, {"0.9", nil}},
+	}}
+
+	pins, err := resolveNames(source, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "2.0", "b": "1.5"}, pins)
+}
+
+func TestResolver_Yanked(t *testing.T) {
+	source := &memorySource{
+		releases: map[string][]memoryRelease{
+			"a": {{"2.0", nil}, {"1.0", nil}},
+		},
+		yanked: []string{"a 2.0"},
+	}
+
+	pins, err := resolveNames(source, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1.0"}, pins)
+
+	// The yanked release is selected only if pinned exactly
+	pins, err = resolveNames(source, "a==2.0")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "2.0"}, pins)
+
+	pins, err = resolveNames(source, "a===2.0")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "2.0"}, pins)
+
+	_, err = resolveNames(source, "a>=2.0")
+	assert.ErrorIs(t, err, ferror.NoSuitableVersion)
+}
+
+func TestResolver_Backtracking(t *testing.T) {
+	// The newest "a" requires "b<1.5", but "c" requires "b>=2". The resolver
+	// should step back and choose the older "a"
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a": {{"2.0", []string{"b (<1.5)"}}, {"1.0", []string{"b (>=1)"}}},
+		"b": {{"2.1", nil}, {"1.4", nil}},
+		"c": {{"3.1", []string{"b (>=2)"}}},
+	}}
+
+	pins, err := resolveNames(source, "a", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1.0", "b": "2.1", "c": "3.1"}, pins)
+}
+
+func TestResolver_Backjumping(t *testing.T) {
+	// "x" has nothing to do with the conflict between "a" and "c", so its
+	// older versions should not be tried
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a": {{"1.0", []string{"b (<1.5)"}}},
+		"x": {{"3", nil}, {"2", nil}, {"1", nil}},
+		"c": {{"3.1", []string{"b (>=2)"}}},
+		"b": {{"2.1", nil}, {"1.4", nil}},
+	}}
+
+	_, err := resolveNames(source, "a", "x", "c")
+	assert.ErrorIs(t, err, ferror.NoSuitableVersion)
+	// Neither version of "b" is suitable, so only the first versions of
+	// "a", "x" and "c" are requested
+	assert.Equal(t, 3, source.requests)
+}
+
+func TestResolver_Extras(t *testing.T) {
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a":     {{"1.0", []string{"socks (>=1) ; extra == 'proxy'"}}},
+		"b":     {{"1.0", []string{"a[proxy]"}}},
+		"socks": {{"1.2", nil}},
+	}}
+
+	pins, err := resolveNames(source, "a", "b")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1.0", "b": "1.0", "socks": "1.2"}, pins)
+
+	_, err = resolveNames(source, "a[unknown]")
+	var missingExtra *ferror.MissingExtra
+	assert.ErrorAs(t, err, &missingExtra)
+}
+
+func TestResolver_Cycle(t *testing.T) {
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a": {{"1.0", []string{"b"}}},
+		"b": {{"1.0", []string{"a (>=1)"}}},
+	}}
+
+	pins, err := resolveNames(source, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1.0", "b": "1.0"}, pins)
+}
+
+func TestResolver_NoSuitableVersion(t *testing.T) {
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a": {{"1.0", []string{"missing"}}},
+	}}
+
+	_, err := resolveNames(source, "a")
+	assert.ErrorIs(t, err, ferror.NoSuitableVersion)
+
+	_, err = resolveNames(source, "a>=2")
+	assert.ErrorIs(t, err, ferror.NoSuitableVersion)
+}
+
+func TestResolver_ConflictExplanation(t *testing.T) {
+	source := &memorySource{releases: map[string][]memoryRelease{
+		"a": {{"2.0", []string{"b (<1.5)"}}},
+		"b": {{"2.1", nil}, {"1.4", nil}},
+		"c": {{"3.1", []string{"b (>=2)"}}},
+		"d": {{"1.0", []string{"a"}}},
+	}}
+
+	_, err := resolveNames(source, "d", "c")
+	assert.ErrorIs(t, err, ferror.NoSuitableVersion)
+	assert.Contains(
+		t,
+		err.Error(),
+		"c 3.1 requires b>=2, a 2.0 (via d 1.0) requires b<1.5, so c 3.1 and a 2.0 can't both be installed",
+	)
+	assert.Contains(t, err.Error(), "so no version of a can be installed along with c 3.1 and d 1.0")
+
+	_, err = resolveNames(source, "a", "b>=2")
+	assert.Contains(t, err.Error(), "b>=2 is requested, a 2.0 requires b<1.5, so a 2.0 can't be installed")
+
+	_, err = resolveNames(source, "c", "b>=3")
+	assert.Contains(t, err.Error(), "b>=3 is requested, but no suitable version of b was found")
+}
@@ -0,0 +1,195 @@
+package installer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// Keys of the installation scheme paths used as the names of subdirectories in
+// the data directory of the wheel
+// (https://packaging.python.org/en/latest/specifications/binary-distribution-format/#the-data-directory).
+const (
+	schemePurelib = "purelib"
+	schemePlatlib = "platlib"
+	schemeScripts = "scripts"
+	schemeHeaders = "headers"
+	schemeData    = "data"
+)
+
+// stagedTarget is the staging directory with the files installed outside
+// config.PythonLibPath, e.g. scripts or data files.
+type stagedTarget struct {
+	// Path to the staging directory, which is moved to the destination as a
+	// whole
+	dir string
+	// Path to the existing directory the staged files are moved to
+	dst string
+	// Path inside the staging directory that corresponds to the scheme path.
+	// It differs from dir if the scheme path doesn't exist yet, so the missing
+	// directories are created by the move as well
+	root string
+}
+
+// newTarget returns the staging target for the scheme path. The directories
+// are not created until the files are written to the target.
+func (t *transaction) newTarget(schemePath string) *stagedTarget {
+	dst := filepath.Clean(schemePath)
+	for {
+		if _, err := os.Stat(dst); err == nil || filepath.Dir(dst) == dst {
+			break
+		}
+		dst = filepath.Dir(dst)
+	}
+
+	dir := t.newStagingPath()
+	rel, err := filepath.Rel(dst, schemePath)
+	if err != nil {
+		rel = "."
+	}
+
+	return &stagedTarget{dir: dir, dst: dst, root: filepath.Join(dir, rel)}
+}
+
+// record returns the RECORD entries of the staged files with the paths they
+// have after being moved to the destination, and the paths of the executable
+// ones among them. The paths are relative to config.PythonLibPath, or absolute
+// if the files are installed on another drive.
+// Returns an error if any file can't be read.
+func (st *stagedTarget) record() ([]pkg.RecordEntry, []string, error) {
+	if _, err := os.Stat(st.dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+
+	var entries []pkg.RecordEntry
+	var executables []string
+	err := filepath.Walk(st.dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		entry, err := pkg.NewRecordEntry(st.dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(st.dst, filepath.FromSlash(entry.Path))
+		if rel, err := filepath.Rel(config.PythonLibPath, path); err == nil {
+			path = rel
+		}
+		entry.Path = filepath.ToSlash(path)
+		entries = append(entries, entry)
+		if info.Mode()&0111 != 0 {
+			executables = append(executables, entry.Path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, executables, nil
+}
+
+// getDataDirectoryName returns the name of the wheel data directory that
+// belongs to the meta-directory.
+func getDataDirectoryName(metaDirName string) string {
+	return strings.TrimSuffix(metaDirName, ".dist-info") + ".data"
+}
+
+// installDataDirectory moves the subdirectories of the wheel data directory to
+// their installation scheme paths. The "purelib" and "platlib" ones are merged
+// into the staged package, the others are moved to the targets. Scripts are
+// made executable, and their "#!python" shebangs are rewritten. The data
+// directory is removed afterward, so it is not installed.
+// Returns ferror.UnexpectedScheme if the subdirectory doesn't match any scheme
+// path, or ferror.DuplicateEntry if the file is installed twice.
+func installDataDirectory(dataDir, staged string, targets map[string]*stagedTarget) error {
+	entries, err := os.ReadDir(dataDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		src := filepath.Join(dataDir, entry.Name())
+		if !entry.IsDir() {
+			return &ferror.UnexpectedScheme{Scheme: entry.Name()}
+		}
+
+		var dst string
+		switch entry.Name() {
+		case schemePurelib, schemePlatlib:
+			dst = staged
+		case schemeScripts, schemeHeaders, schemeData:
+			dst = targets[entry.Name()].root
+		default:
+			return &ferror.UnexpectedScheme{Scheme: entry.Name()}
+		}
+
+		if entry.Name() == schemeScripts {
+			if err = prepareScripts(src); err != nil {
+				return err
+			}
+		}
+
+		if err = mergeDirectory(src, dst); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(dataDir)
+}
+
+// prepareScripts makes all the scripts in the directory executable.
+func prepareScripts(dir string) error {
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		return io.PrepareScript(filePath)
+	})
+}
+
+// mergeDirectory moves the content of the source directory into the
+// destination one, which is created if it doesn't exist.
+// Returns ferror.DuplicateEntry if the file exists in both directories.
+func mergeDirectory(src, dst string) error {
+	dstInfo, err := os.Lstat(dst)
+	if errors.Is(err, os.ErrNotExist) {
+		if err = os.MkdirAll(filepath.Dir(dst), config.DefaultChmod); err != nil {
+			return err
+		}
+		return os.Rename(src, dst)
+	} else if err != nil {
+		return err
+	}
+
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	} else if !srcInfo.IsDir() || !dstInfo.IsDir() {
+		return &ferror.DuplicateEntry{Path: filepath.Base(dst)}
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = mergeDirectory(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,471 @@
+package installer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/io/web"
+	"github.com/fextpkg/cli/fext/pkg"
+	"github.com/fextpkg/cli/fext/ui"
+)
+
+// candidate is a specific version of a package that can be pinned during the
+// dependency resolution.
+type candidate struct {
+	// Normalized package name
+	name string
+	// Package version
+	version string
+	// Distribution file in the repository. It is nil if the candidate is
+	// already installed in the system
+	remote *web.Candidate
+	// Path to the downloaded distribution file. It stays empty until the
+	// metadata of the remote candidate is requested
+	filePath string
+	// Package metadata. It is loaded lazily for the remote candidates
+	metadata *pkg.Package
+	// Core metadata served by the repository separately from the
+	// distribution file (PEP 658). It must match the METADATA file of the
+	// distribution, which is checked before the installation
+	indexMetadata []byte
+	// URL of the direct reference (PEP 508) the candidate was requested by,
+	// without the fragment and the credentials. It is empty for the
+	// candidates found in the index
+	directURL string
+}
+
+// isInstalled checks if the candidate is the package installed in the system.
+func (c *candidate) isInstalled() bool {
+	return c.remote == nil
+}
+
+// isYanked checks if the distribution file of the candidate was yanked from
+// the repository (PEP 592).
+func (c *candidate) isYanked() bool {
+	return c.remote != nil && c.remote.Yanked
+}
+
+// warnYanked prints a warning with the reason if the distribution file of the
+// candidate was yanked.
+func warnYanked(c *candidate) {
+	if !c.isYanked() {
+		return
+	}
+
+	reason := c.remote.YankedReason
+	if reason == "" {
+		reason = "no reason given"
+	}
+	ui.PrintfWarning("%s has been yanked: %s\n", c, reason)
+}
+
+func (c *candidate) String() string {
+	return c.name + " " + c.version
+}
+
+// candidateSource provides the resolver with the package candidates and their
+// metadata.
+type candidateSource interface {
+	// getCandidates returns all candidates of the package compatible with
+	// the system, in order of preference.
+	getCandidates(pkgName string) ([]*candidate, error)
+	// getMetadata returns the metadata of the candidate.
+	getMetadata(c *candidate) (*pkg.Package, error)
+}
+
+// indexSource looks for candidates among the installed packages, in the local
+// find-links directories and in the package indexes. Distribution files are
+// downloaded to read their metadata and are kept until the installation is
+// over.
+type indexSource struct {
+	// Candidates that have already been requested, by the package name
+	candidates map[string][]*candidate
+	// Paths to the downloaded distribution files
+	downloads []string
+	// Allowed hashes of the distribution files, by the package name
+	hashes map[string][]string
+	// Determines if the distribution files can be downloaded only when their
+	// hashes are known
+	requireHashes bool
+	// Do not offer the installed packages as candidates
+	ignoreInstalled bool
+	// URLs of the distribution files requested by the direct references,
+	// by the package name. Such packages are not looked up in the index
+	directURLs map[string]string
+	// Local directories with the distribution files, which are looked up
+	// along with the index
+	findLinks []string
+	// Do not access the network. Only the find-links directories and the
+	// local files are used
+	offline bool
+	// URLs of the package indexes in the Simple API format, the main one
+	// first
+	indexes []string
+	// URLs of the indexes the packages are pinned to, by the package name.
+	// Pinned packages are not looked up in the other indexes
+	packageIndexes map[string]string
+}
+
+// getInstalled loads the installed package. Returns nil without an error if
+// the package is not installed, or the installed packages are ignored.
+func (s *indexSource) getInstalled(pkgName string) (*pkg.Package, error) {
+	if s.ignoreInstalled {
+		return nil, nil
+	}
+
+	p, err := pkg.Load(pkgName)
+	if errors.Is(err, ferror.PackageDirectoryMissing) {
+		return nil, nil
+	}
+
+	return p, err
+}
+
+// getCandidates returns the installed version of the package first, if any,
+// since it is preferable to keep it. Then come the versions available in the
+// find-links directories and the repository, from the newest to the oldest.
+// Returns ferror.MissingHashes if the package has no hashes in the
+// hash-checking mode and is not installed, so the resolution fails before
+// anything is downloaded.
+func (s *indexSource) getCandidates(pkgName string) ([]*candidate, error) {
+	if candidates, ok := s.candidates[pkgName]; ok {
+		return candidates, nil
+	}
+	if link, ok := s.directURLs[pkgName]; ok {
+		return s.getDirectCandidates(pkgName, link)
+	}
+
+	var candidates []*candidate
+	p, err := s.getInstalled(pkgName)
+	if err != nil {
+		return nil, err
+	} else if p != nil {
+		candidates = append(candidates, &candidate{
+			name:     pkgName,
+			version:  p.Version,
+			metadata: p,
+		})
+	}
+
+	if s.requireHashes && len(s.hashes[pkgName]) == 0 {
+		// The files without hashes can't be downloaded in the hash-checking
+		// mode, so the dependencies the user hasn't pinned can only be
+		// satisfied with the installed versions
+		if len(candidates) == 0 {
+			return nil, &ferror.MissingHashes{Package: pkgName}
+		}
+		s.candidates[pkgName] = candidates
+		return candidates, nil
+	}
+
+	remote, err := s.getRemoteCandidates(pkgName)
+	if err != nil && !(isNotFound(err) && len(candidates) > 0) {
+		return nil, err
+	}
+
+	for _, c := range remote {
+		if p != nil {
+			// The installed version is already on the list
+			equal, _ := expression.CompareVersion(c.Version, "==", p.Version)
+			if equal {
+				continue
+			}
+		}
+
+		candidates = append(candidates, &candidate{
+			name:    pkgName,
+			version: c.Version,
+			remote:  c,
+		})
+	}
+
+	s.candidates[pkgName] = candidates
+	return candidates, nil
+}
+
+// getRemoteCandidates looks for the distribution files of the package in the
+// find-links directories and, unless the source is offline, in the indexes.
+// The versions found in several places are taken from the first one: local
+// files are preferred, then the indexes in the given order.
+// Returns ferror.NoSuitableVersion or ferror.PackageNotFound if a suitable
+// version was not found.
+func (s *indexSource) getRemoteCandidates(pkgName string) ([]*web.Candidate, error) {
+	var lists [][]*web.Candidate
+	var notFound error
+
+	if len(s.findLinks) > 0 {
+		local, err := web.NewRequest(pkgName, nil).GetLocalCandidates(s.findLinks)
+		if err != nil && !isNotFound(err) {
+			return nil, err
+		}
+		lists = append(lists, local)
+	}
+
+	for _, indexURL := range s.getIndexes(pkgName) {
+		indexed, err := web.NewIndexRequest(indexURL, pkgName, nil).GetCandidates()
+		if isNotFound(err) {
+			// The package may be found in another index. It's more helpful
+			// to report that no version is suitable, if any index knows the
+			// package
+			if notFound == nil || errors.Is(err, ferror.NoSuitableVersion) {
+				notFound = err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		lists = append(lists, indexed)
+	}
+
+	candidates := web.MergeCandidates(lists...)
+	if len(candidates) == 0 {
+		if notFound == nil {
+			notFound = ferror.NoSuitableVersion
+		}
+		return nil, notFound
+	}
+
+	return candidates, nil
+}
+
+// getIndexes returns the URLs of the indexes the package is looked up in. If
+// the package is pinned to an index, only that index is used, so the package
+// can't be substituted with the one of the same name from another index.
+// There are no indexes in the offline mode.
+func (s *indexSource) getIndexes(pkgName string) []string {
+	if s.offline {
+		return nil
+	} else if indexURL, ok := s.packageIndexes[pkgName]; ok {
+		return []string{indexURL}
+	}
+
+	return s.indexes
+}
+
+// getAllIndexes returns the URLs of all the configured indexes, including the
+// ones the packages are pinned to. The pinned indexes are sorted, so the
+// result doesn't depend on the order of the map.
+func (s *indexSource) getAllIndexes() []string {
+	var pinned []string
+	for _, indexURL := range s.packageIndexes {
+		pinned = append(pinned, indexURL)
+	}
+	sort.Strings(pinned)
+
+	return append(append([]string{}, s.indexes...), pinned...)
+}
+
+// isNotFound checks if the error means that no suitable version of the
+// package was found.
+func isNotFound(err error) bool {
+	var missing *ferror.PackageNotFound
+	return errors.Is(err, ferror.NoSuitableVersion) || errors.As(err, &missing)
+}
+
+// getDirectCandidates returns the candidate of the distribution file the
+// direct reference points to. Local files are used in place, and their hashes
+// are verified right away. The installed package is offered instead if it was
+// installed from the same URL in the same version.
+// Returns ferror.IncompatibleFile if the file is not a wheel compatible with
+// the system.
+func (s *indexSource) getDirectCandidates(pkgName, link string) ([]*candidate, error) {
+	fileName := web.GetLinkFileName(link)
+	if !web.IsCompatible(fileName) {
+		return nil, &ferror.IncompatibleFile{Package: pkgName, File: fileName}
+	}
+	_, version, _ := web.ParseWheelFileName(fileName)
+	// The credentials must not be stored in "direct_url.json" (PEP 610)
+	directURL := web.StripCredentials(web.StripFragment(link))
+
+	p, err := s.getInstalled(pkgName)
+	if err != nil {
+		return nil, err
+	} else if p != nil && p.Version == version {
+		installed, err := p.GetDirectURL()
+		if err != nil {
+			return nil, err
+		} else if installed != nil && web.StripCredentials(installed.URL) == directURL {
+			s.candidates[pkgName] = []*candidate{{name: pkgName, version: p.Version, metadata: p}}
+			return s.candidates[pkgName], nil
+		}
+	}
+
+	c := &candidate{
+		name:      pkgName,
+		version:   version,
+		remote:    &web.Candidate{FileName: fileName, Version: version, Link: link},
+		directURL: directURL,
+	}
+
+	if filePath, ok := web.GetLocalPath(link); ok {
+		// The local file is not removed after the installation
+		hashes := s.hashes[pkgName]
+		if s.requireHashes && len(hashes) == 0 {
+			return nil, &ferror.MissingHashes{Package: pkgName}
+		} else if len(hashes) > 0 {
+			if err = web.VerifyFile(filePath, hashes); err != nil {
+				return nil, err
+			}
+		}
+		c.filePath = filePath
+	}
+
+	s.candidates[pkgName] = []*candidate{c}
+	return s.candidates[pkgName], nil
+}
+
+// getMetadata reads the metadata of the remote candidate. If the repository
+// serves the core metadata separately, only it is downloaded, so the whole
+// distribution file is downloaded later only if the candidate is installed.
+// Otherwise, the distribution file is downloaded and the metadata is read
+// from it. The metadata of the installed candidates is loaded in advance.
+// Returns ferror.HashMismatch if the metadata doesn't match its digest, or any
+// error returned by download.
+func (s *indexSource) getMetadata(c *candidate) (*pkg.Package, error) {
+	if c.metadata != nil {
+		return c.metadata, nil
+	}
+
+	if s.canGetIndexMetadata(c) {
+		data, err := web.NewRequest(c.name, nil).GetMetadata(c.remote)
+		var mismatch *ferror.HashMismatch
+		if err == nil {
+			c.indexMetadata = data
+			c.metadata = pkg.LoadFromMetadata(data)
+			return c.metadata, nil
+		} else if errors.As(err, &mismatch) {
+			return nil, err
+		}
+		// The metadata may be unavailable despite being advertised, the
+		// distribution file is used instead
+	}
+
+	if err := s.download(c); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadPackageMetadata(c.filePath)
+	if err != nil {
+		return nil, err
+	}
+	c.metadata = pkg.LoadFromMetadata(data)
+
+	return c.metadata, nil
+}
+
+// canGetIndexMetadata checks if the core metadata of the candidate can be
+// downloaded separately from the distribution file. Local files are read
+// directly instead.
+func (s *indexSource) canGetIndexMetadata(c *candidate) bool {
+	if c.filePath != "" || !c.remote.HasMetadata || s.offline {
+		return false
+	}
+
+	_, local := s.findLocalFile(c.remote)
+	return !local
+}
+
+// download downloads the distribution file of the remote candidate, unless it
+// has already been downloaded. Local files, including the ones found in the
+// find-links directories by the file name, are used in place. If hashes are
+// provided for the package, the file must match one of them.
+// Returns ferror.MissingHashes if the hashes are required, but not provided,
+// ferror.HashMismatch if the file doesn't match them, or
+// ferror.OfflineDownload if the file is not available locally in the offline
+// mode.
+func (s *indexSource) download(c *candidate) error {
+	if c.filePath != "" {
+		return nil
+	}
+
+	hashes := s.hashes[c.name]
+	if s.requireHashes && len(hashes) == 0 {
+		return &ferror.MissingHashes{Package: c.name}
+	}
+
+	if filePath, ok := s.findLocalFile(c.remote); ok {
+		// The file is not removed after the installation. The digest
+		// provided by the repository is verified here, since the file is not
+		// downloaded
+		if c.remote.Hash != "" {
+			if err := verifyFile(filePath, c.remote.FileName, []string{c.remote.Hash}); err != nil {
+				return err
+			}
+		}
+		c.filePath = filePath
+	} else if s.offline {
+		return &ferror.OfflineDownload{File: c.remote.FileName}
+	} else {
+		filePath, err := web.NewRequest(c.name, nil).DownloadPackage(c.remote.Link)
+		if err != nil {
+			return err
+		}
+		c.filePath = filePath
+		s.downloads = append(s.downloads, filePath)
+	}
+
+	if len(hashes) > 0 {
+		// The downloaded file is kept in the downloads list and will be
+		// removed along with the others
+		return verifyFile(c.filePath, c.remote.FileName, hashes)
+	}
+
+	return nil
+}
+
+// verifyFile checks that the distribution file matches one of the hashes. The
+// mismatch is reported with the name of the file rather than the local path.
+func verifyFile(filePath, fileName string, hashes []string) error {
+	err := web.VerifyFile(filePath, hashes)
+	var mismatch *ferror.HashMismatch
+	if errors.As(err, &mismatch) {
+		mismatch.File = fileName
+	}
+
+	return err
+}
+
+// findLocalFile returns the path to the distribution file if it is stored
+// locally, either the link points to the local file, or a file with the same
+// name is found in the find-links directories.
+func (s *indexSource) findLocalFile(remote *web.Candidate) (string, bool) {
+	if filePath, ok := web.GetLocalPath(remote.Link); ok {
+		return filePath, true
+	}
+
+	for _, dir := range s.findLinks {
+		filePath := filepath.Join(dir, remote.FileName)
+		if info, err := os.Stat(filePath); err == nil && info.Mode().IsRegular() {
+			return filePath, true
+		}
+	}
+
+	return "", false
+}
+
+// cleanup removes all downloaded distribution files.
+func (s *indexSource) cleanup() error {
+	for _, filePath := range s.downloads {
+		if err := os.RemoveAll(filePath); err != nil {
+			return err
+		}
+	}
+	s.downloads = nil
+
+	return nil
+}
+
+func newIndexSource() *indexSource {
+	return &indexSource{
+		candidates:     map[string][]*candidate{},
+		hashes:         map[string][]string{},
+		directURLs:     map[string]string{},
+		indexes:        []string{web.DefaultIndexURL},
+		packageIndexes: map[string]string{},
+	}
+}
@@ -0,0 +1,260 @@
+package installer
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+	"github.com/fextpkg/cli/fext/pkg"
+)
+
+// newWheelhouse creates a find-links directory with the wheels of the package
+// containing "hello", and returns the path to it.
+func newWheelhouse(t *testing.T, fileNames ...string) string {
+	dir := t.TempDir()
+	for _, fileName := range fileNames {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, fileName), []byte("hello"), 0644))
+	}
+
+	return dir
+}
+
+func TestIndexSource_getCandidates_Offline(t *testing.T) {
+	setupLibPath(t)
+	dir := newWheelhouse(t, "my_lib-1.0-py3-none-any.whl", "my_lib-2.0-py3-none-any.whl")
+
+	s := newIndexSource()
+	s.findLinks = []string{dir}
+	s.offline = true
+
+	candidates, err := s.getCandidates("my-lib")
+	assert.Nil(t, err)
+	assert.Len(t, candidates, 2)
+	assert.Equal(t, "2.0", candidates[0].version)
+	assert.Equal(t, "1.0", candidates[1].version)
+
+	// The local file is used in place and is not removed afterward
+	assert.Nil(t, s.download(candidates[0]))
+	assert.Equal(t, filepath.Join(dir, "my_lib-2.0-py3-none-any.whl"), candidates[0].filePath)
+	assert.Empty(t, s.downloads)
+
+	_, err = s.getCandidates("missing")
+	assert.ErrorIs(t, err, ferror.NoSuitableVersion)
+}
+
+func TestIndexSource_download_FindLinks(t *testing.T) {
+	dir := newWheelhouse(t, "my_lib-1.0-py3-none-any.whl")
+	s := newIndexSource()
+	s.findLinks = []string{dir}
+	s.offline = true
+
+	// The locked file is found in the find-links directory by its name
+	c := &candidate{
+		name:    "my-lib",
+		version: "1.0",
+		remote: &web.Candidate{
+			FileName: "my_lib-1.0-py3-none-any.whl",
+			Link:     "https://host/my_lib-1.0-py3-none-any.whl#sha256=" + digest,
+			Hash:     "sha256:" + digest,
+		},
+	}
+	assert.Nil(t, s.download(c))
+	assert.Equal(t, filepath.Join(dir, "my_lib-1.0-py3-none-any.whl"), c.filePath)
+
+	c.filePath = ""
+	c.remote.Hash = "sha256:0123"
+	var mismatch *ferror.HashMismatch
+	assert.ErrorAs(t, s.download(c), &mismatch)
+	assert.Equal(t, "my_lib-1.0-py3-none-any.whl", mismatch.File)
+	assert.Empty(t, c.filePath)
+
+	c.remote.FileName = "my_lib-2.0-py3-none-any.whl"
+	var offline *ferror.OfflineDownload
+	assert.ErrorAs(t, s.download(c), &offline)
+}
+
+// newIndexServer serves the Simple API pages listing the wheels of the
+// packages, in the same layout as PyPi does.
+func newIndexServer(packages map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/simple/"), "/")
+		files, ok := packages[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		page := "<!DOCTYPE html>\n<html>\n  <head></head>\n  <body>\n"
+		for _, fileName := range files {
+			page += `    <a href="/files/` + fileName + `">` + fileName + "</a><br />\n"
+		}
+		_, _ = w.Write([]byte(page + "  </body>\n</html>\n"))
+	}))
+}
+
+func TestIndexSource_getCandidates_Indexes(t *testing.T) {
+	setupLibPath(t)
+	public := newIndexServer(map[string][]string{
+		"corp-lib": {"corp_lib-9.0-py3-none-any.whl"},
+		"requests": {"requests-2.0-py3-none-any.whl"},
+	})
+	defer public.Close()
+	private := newIndexServer(map[string][]string{
+		"corp-lib": {"corp_lib-1.0-py3-none-any.whl"},
+		"requests": {"requests-1.0-py3-none-any.whl", "requests-2.0-py3-none-any.whl"},
+	})
+	defer private.Close()
+
+	opt := DefaultOptions()
+	opt.IndexURL = public.URL + "/simple/"
+	opt.ExtraIndexURLs = []string{private.URL + "/simple/"}
+	opt.PackageIndexes = map[string]string{"Corp_Lib": private.URL + "/simple/"}
+	s := NewInstaller(opt).source
+
+	// The pinned package is never taken from the other indexes
+	candidates, err := s.getCandidates("corp-lib")
+	assert.Nil(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, "1.0", candidates[0].version)
+	assert.True(t, strings.HasPrefix(candidates[0].remote.Link, private.URL))
+
+	// The same version is taken from the main index
+	candidates, err = s.getCandidates("requests")
+	assert.Nil(t, err)
+	assert.Len(t, candidates, 2)
+	assert.True(t, strings.HasPrefix(candidates[0].remote.Link, public.URL))
+	assert.True(t, strings.HasPrefix(candidates[1].remote.Link, private.URL))
+
+	_, err = s.getCandidates("missing")
+	var notFound *ferror.PackageNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+// newMetadataServer serves the project pages in the JSON format that
+// advertise the core metadata of the files, the metadata itself and the
+// wheels. The metadata of the "broken" package is missing.
+func newMetadataServer(t *testing.T, metadata string) (*httptest.Server, *[]string) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("pkg-1.0.dist-info/METADATA")
+	assert.Nil(t, err)
+	_, err = f.Write([]byte(metadata))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/simple/pkg/", "/simple/broken/":
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/simple/"), "/")
+			w.Header().Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+			_, _ = w.Write([]byte(`{"meta": {"api-version": "1.1"}, "files": [{"filename": "` + name +
+				`-1.0-py3-none-any.whl", "url": "/files/` + name + `-1.0-py3-none-any.whl", "hashes": {}, "core-metadata": true}]}`))
+		case "/files/pkg-1.0-py3-none-any.whl.metadata":
+			_, _ = w.Write([]byte(metadata))
+		case "/files/pkg-1.0-py3-none-any.whl", "/files/broken-1.0-py3-none-any.whl":
+			_, _ = w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return server, &requested
+}
+
+func TestIndexSource_getMetadata_CoreMetadata(t *testing.T) {
+	setupLibPath(t)
+	downloadPath := config.DownloadPath
+	t.Cleanup(func() { config.DownloadPath = downloadPath })
+	config.DownloadPath = t.TempDir()
+
+	server, requested := newMetadataServer(t, "Name: pkg\nVersion: 1.0\nRequires-Dist: dep\n")
+	defer server.Close()
+	s := newIndexSource()
+	s.indexes = []string{server.URL + "/simple/"}
+
+	// Only the metadata is downloaded to learn the dependencies
+	candidates, err := s.getCandidates("pkg")
+	assert.Nil(t, err)
+	p, err := s.getMetadata(candidates[0])
+	assert.Nil(t, err)
+	dependencies, err := p.GetDependencies()
+	assert.Nil(t, err)
+	assert.Equal(t, "dep", dependencies[0].PackageName)
+	assert.Equal(t, []string{"/simple/pkg/", "/files/pkg-1.0-py3-none-any.whl.metadata"}, *requested)
+	assert.Empty(t, candidates[0].filePath)
+	assert.NotNil(t, candidates[0].indexMetadata)
+
+	// The missing metadata is read from the wheel instead
+	candidates, err = s.getCandidates("broken")
+	assert.Nil(t, err)
+	p, err = s.getMetadata(candidates[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "pkg", p.Name)
+	assert.NotEmpty(t, candidates[0].filePath)
+	assert.Nil(t, candidates[0].indexMetadata)
+	assert.Nil(t, s.cleanup())
+}
+
+func TestIndexSource_getCandidates_RequireHashes(t *testing.T) {
+	setupLibPath(t)
+	s := newIndexSource()
+	s.requireHashes = true
+	s.offline = true
+
+	// The dependency without hashes fails the resolution before anything is
+	// downloaded
+	_, err := s.getCandidates("dep")
+	var missingHashes *ferror.MissingHashes
+	assert.ErrorAs(t, err, &missingHashes)
+	assert.Equal(t, "dep", missingHashes.Package)
+}
+
+func TestInstaller_GetRemoteStatus(t *testing.T) {
+	setupLibPath(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/a/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+		_, _ = w.Write([]byte(`{"meta": {"api-version": "1.1"}, "files": [
+			{"filename": "a-1.0-py3-none-any.whl", "url": "a-1.0-py3-none-any.whl", "hashes": {}, "yanked": "broken"},
+			{"filename": "a-2.0-py3-none-any.whl", "url": "a-2.0-py3-none-any.whl", "hashes": {}},
+			{"filename": "a-3.0-py3-none-any.whl", "url": "a-3.0-py3-none-any.whl", "hashes": {}, "yanked": true}
+		]}`))
+	}))
+	defer server.Close()
+
+	opt := DefaultOptions()
+	opt.IndexURL = server.URL + "/simple/"
+	i := NewInstaller(opt)
+
+	// The yanked versions are never the latest ones
+	status, err := i.GetRemoteStatus(pkg.LoadFromMetadata([]byte("Name: a\nVersion: 1.0\n")))
+	assert.Nil(t, err)
+	assert.True(t, status.IsOutdated())
+	assert.Equal(t, "2.0", status.Latest)
+	assert.True(t, status.Yanked)
+	assert.Equal(t, "broken", status.YankedReason)
+
+	statuses, errs := i.GetRemoteStatuses([]*pkg.Package{
+		pkg.LoadFromMetadata([]byte("Name: a\nVersion: 2.0\n")),
+		pkg.LoadFromMetadata([]byte("Name: missing\nVersion: 1.0\n")),
+	})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.False(t, statuses[0].IsOutdated())
+	assert.False(t, statuses[0].Yanked)
+	assert.Empty(t, statuses[1].Latest)
+}
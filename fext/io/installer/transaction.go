@@ -0,0 +1,390 @@
+package installer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io"
+	"github.com/fextpkg/cli/fext/ui"
+)
+
+// transactionDirName is the name of the directory in config.PythonLibPath
+// where the transaction keeps its journal, the staged packages and the files
+// moved aside. It is located on the same filesystem as the packages, so the
+// files are moved by renaming. The files of the other installation scheme
+// paths, e.g. scripts, are copied if the path is located on another filesystem.
+const transactionDirName = ".fext-transaction"
+
+// lockFileName is the name of the file in config.PythonLibPath locked by the
+// process that changes the packages. It is located outside the transaction
+// directory, since the directory is removed after each transaction.
+const lockFileName = ".fext-transaction.lock"
+
+// journalFileName is the name of the transaction journal file.
+const journalFileName = "journal"
+
+// Operations recorded in the transaction journal
+const (
+	// The file was moved into the place where nothing existed
+	opCreate = "create"
+	// The existing file was moved aside to the backup directory
+	opBackup = "backup"
+)
+
+// journalEntry is an operation made by the transaction. Each entry is recorded
+// in the journal before the operation itself is made, so the journal is enough
+// to revert the changes if the process is killed.
+type journalEntry struct {
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Backup string `json:"backup,omitempty"`
+}
+
+// revert undoes the operation. It is safe to revert an operation that has not
+// been made.
+func (e journalEntry) revert() error {
+	switch e.Op {
+	case opCreate:
+		if err := os.RemoveAll(io.GetPartialPath(e.Path)); err != nil {
+			return err
+		}
+		return os.RemoveAll(e.Path)
+	case opBackup:
+		if _, err := os.Lstat(e.Backup); errors.Is(err, os.ErrNotExist) {
+			// The file hasn't been moved yet
+			return nil
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			return err
+		}
+		return io.MovePath(e.Backup, e.Path)
+	}
+
+	return nil
+}
+
+// transaction makes the changes in config.PythonLibPath revertible. New
+// packages are extracted into the staging directory first, then moved into
+// place. The files being replaced or removed are moved aside instead of being
+// deleted. If anything goes wrong, the transaction is rolled back, and the
+// previous state of the packages is restored.
+type transaction struct {
+	// Guards the operations, so the rollback caused by the interrupt signal
+	// doesn't interfere with them
+	mu sync.Mutex
+	// Path to the transaction directory
+	dir string
+	// Opened journal file
+	journal *os.File
+	// Operations made by the transaction, in order
+	entries []journalEntry
+	// Counter used to name the staging and backup directories
+	counter int
+	// Determines if the transaction is committed or rolled back
+	done bool
+}
+
+// getTransactionDir returns the path to the transaction directory.
+func getTransactionDir() string {
+	return filepath.Join(config.PythonLibPath, transactionDirName)
+}
+
+// LockPackages locks config.PythonLibPath, so only one process changes the
+// packages at once. The lock must be held for the whole life of the process
+// that installs or removes the packages, it is released by the system when the
+// process exits.
+// Returns ferror.PackagesLocked if another process holds the lock.
+func LockPackages() (*io.FileLock, error) {
+	if err := os.MkdirAll(config.PythonLibPath, config.DefaultChmod); err != nil {
+		return nil, err
+	}
+
+	l, locked, err := io.TryLockFile(filepath.Join(config.PythonLibPath, lockFileName))
+	if err != nil {
+		return nil, err
+	} else if !locked {
+		return nil, ferror.PackagesLocked
+	}
+
+	return l, nil
+}
+
+// beginTransaction creates the transaction directory and the journal.
+// Transactions can't be nested, so any interrupted transaction must be
+// recovered before. The caller must hold the lock of the packages.
+func beginTransaction() (*transaction, error) {
+	dir := getTransactionDir()
+	if err := os.Mkdir(dir, config.DefaultChmod); err != nil {
+		return nil, err
+	}
+
+	journal, err := os.Create(filepath.Join(dir, journalFileName))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &transaction{dir: dir, journal: journal}, nil
+}
+
+// nextPath returns a new unique path in the transaction directory.
+func (t *transaction) nextPath(prefix string) string {
+	t.counter++
+	return filepath.Join(t.dir, prefix+strconv.Itoa(t.counter))
+}
+
+// record writes the entry to the journal and flushes it to the disk.
+func (t *transaction) record(e journalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if _, err = t.journal.Write(append(data, '\n')); err != nil {
+		return err
+	} else if err = t.journal.Sync(); err != nil {
+		return err
+	}
+	t.entries = append(t.entries, e)
+
+	return nil
+}
+
+// newStagingPath returns a new path for the staging directory. The directory
+// itself is not created.
+func (t *transaction) newStagingPath() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.nextPath("staging-")
+}
+
+// stage extracts the wheel archive into a new staging directory and returns
+// the path to it. Nothing is changed in config.PythonLibPath.
+func (t *transaction) stage(filePath string) (string, error) {
+	dir := t.newStagingPath()
+	return dir, io.ExtractPackage(filePath, dir)
+}
+
+// remove moves the file or directory aside. Missing paths are ignored.
+func (t *transaction) remove(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.removeLocked(path)
+}
+
+func (t *transaction) removeLocked(path string) error {
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	e := journalEntry{Op: opBackup, Path: path, Backup: t.nextPath("backup-")}
+	if err := t.record(e); err != nil {
+		return err
+	}
+
+	return io.MovePath(e.Path, e.Backup)
+}
+
+// move moves the staged files into the destination directory. Directories
+// that already exist there, e.g. namespace packages, are merged, and the
+// existing files are moved aside. New directories are moved by a single
+// rename, so a partially moved module is never importable. Meta-directories
+// are moved last, so the package becomes visible only when all its files are
+// in place.
+func (t *transaction) move(src, dst string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.moveLocked(src, dst)
+}
+
+func (t *transaction) moveLocked(src, dst string) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	dstInfo, err := os.Lstat(dst)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	} else if err == nil && srcInfo.IsDir() && dstInfo.IsDir() {
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		sort.SliceStable(entries, func(a, b int) bool {
+			return !isMetaDir(entries[a].Name()) && isMetaDir(entries[b].Name())
+		})
+
+		for _, entry := range entries {
+			if err = t.moveLocked(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	} else if err == nil {
+		if err = t.removeLocked(dst); err != nil {
+			return err
+		}
+	}
+
+	if err = t.record(journalEntry{Op: opCreate, Path: dst}); err != nil {
+		return err
+	}
+
+	return io.MovePath(src, dst)
+}
+
+// isMetaDir checks if the name is the name of the package meta-directory.
+func isMetaDir(name string) bool {
+	return strings.HasSuffix(name, ".dist-info")
+}
+
+// finish closes the journal and removes the transaction directory. The
+// journal is removed first, so the transaction can't be recovered even if the
+// process is killed before the rest of the directory is removed. It does
+// nothing if the transaction is already finished.
+func (t *transaction) finish() error {
+	if t.done {
+		return nil
+	}
+
+	t.done = true
+	if err := t.journal.Close(); err != nil {
+		return err
+	} else if err = os.Remove(t.journal.Name()); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(t.dir)
+}
+
+// commit keeps the changes and removes the files moved aside.
+func (t *transaction) commit() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.finish()
+}
+
+// rollback reverts all the changes in the reverse order.
+func (t *transaction) rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.rollbackLocked()
+}
+
+func (t *transaction) rollbackLocked() error {
+	if t.done {
+		return nil
+	} else if err := revertEntries(t.entries); err != nil {
+		// The journal is kept to recover the transaction later
+		return err
+	}
+
+	return t.finish()
+}
+
+// handleInterrupt rolls back the transaction and terminates the process when
+// it is interrupted, e.g. with Ctrl-C. Returns the function that stops
+// handling the signals.
+func (t *transaction) handleInterrupt() func() {
+	signals := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			t.mu.Lock()
+			if err := t.rollbackLocked(); err != nil {
+				ui.PrintlnError("Unable to roll back the installation:", err.Error())
+			} else {
+				ui.PrintlnError("Installation interrupted, changes were rolled back")
+			}
+			os.Exit(130)
+		case <-stop:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(stop)
+	}
+}
+
+// revertEntries reverts the operations in the reverse order.
+func revertEntries(entries []journalEntry) error {
+	for n := len(entries) - 1; n >= 0; n-- {
+		if err := entries[n].revert(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readJournal reads the entries from the journal file. The last entry may be
+// incomplete if the process was killed while writing it. In this case, its
+// operation hasn't been made, and the entry is skipped.
+func readJournal(fileName string) ([]journalEntry, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Recover rolls back the transaction left after the process was killed in
+// the middle of the installation. If the journal is missing, the transaction
+// has already been committed, and only the leftover files are removed. The
+// caller must hold the lock of the packages (see LockPackages), so the
+// transaction of the running process is never taken for an interrupted one.
+// Returns true if the transaction has been rolled back.
+func Recover() (bool, error) {
+	dir := getTransactionDir()
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	entries, err := readJournal(filepath.Join(dir, journalFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, os.RemoveAll(dir)
+	} else if err != nil {
+		return false, err
+	}
+
+	if err = revertEntries(entries); err != nil {
+		return false, err
+	}
+
+	return true, os.RemoveAll(dir)
+}
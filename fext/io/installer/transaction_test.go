@@ -0,0 +1,137 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// setupLibPath replaces config.PythonLibPath with a temporary directory that
+// contains an installed package and a namespace package.
+func setupLibPath(t *testing.T) string {
+	libPath := config.PythonLibPath
+	t.Cleanup(func() { config.PythonLibPath = libPath })
+	config.PythonLibPath = t.TempDir()
+
+	writeFiles(t, config.PythonLibPath, map[string]string{
+		"a/__init__.py":          "old",
+		"a-1.0.dist-info/RECORD": "old",
+		"ns/b/__init__.py":       "other",
+	})
+
+	return config.PythonLibPath
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	assert.Nil(t, err)
+
+	return files
+}
+
+// replacePackage replaces the installed package "a" with a new version, which
+// also adds a module to the namespace package.
+func replacePackage(t *testing.T, tx *transaction, libPath string) {
+	staged := tx.nextPath("staging-")
+	writeFiles(t, staged, map[string]string{
+		"a/__init__.py":          "new",
+		"a-2.0.dist-info/RECORD": "new",
+		"ns/c/__init__.py":       "new",
+	})
+
+	assert.Nil(t, tx.remove(filepath.Join(libPath, "a")))
+	assert.Nil(t, tx.remove(filepath.Join(libPath, "a-1.0.dist-info")))
+	assert.Nil(t, tx.remove(filepath.Join(libPath, "a-1.0.data")))
+	assert.Nil(t, tx.move(staged, libPath))
+}
+
+func TestTransaction_Commit(t *testing.T) {
+	libPath := setupLibPath(t)
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+
+	replacePackage(t, tx, libPath)
+	assert.Nil(t, tx.commit())
+
+	assert.Equal(t, map[string]string{
+		"a/__init__.py":          "new",
+		"a-2.0.dist-info/RECORD": "new",
+		"ns/b/__init__.py":       "other",
+		"ns/c/__init__.py":       "new",
+	}, readFiles(t, libPath))
+}
+
+func TestTransaction_Rollback(t *testing.T) {
+	libPath := setupLibPath(t)
+	before := readFiles(t, libPath)
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+
+	replacePackage(t, tx, libPath)
+	assert.Nil(t, tx.rollback())
+	assert.Equal(t, before, readFiles(t, libPath))
+
+	// The transaction can't be finished twice
+	assert.Nil(t, tx.commit())
+}
+
+func TestRecover(t *testing.T) {
+	libPath := setupLibPath(t)
+	before := readFiles(t, libPath)
+
+	recovered, err := Recover()
+	assert.Nil(t, err)
+	assert.False(t, recovered)
+
+	tx, err := beginTransaction()
+	assert.Nil(t, err)
+	replacePackage(t, tx, libPath)
+	// Simulate the process being killed while writing the next entry
+	_, err = tx.journal.WriteString(`{"op":"create","pa`)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.journal.Close())
+
+	recovered, err = Recover()
+	assert.Nil(t, err)
+	assert.True(t, recovered)
+	assert.Equal(t, before, readFiles(t, libPath))
+}
+
+func TestLockPackages(t *testing.T) {
+	setupLibPath(t)
+
+	l, err := LockPackages()
+	assert.Nil(t, err)
+
+	// The lock is exclusive even within the same process, since each call
+	// opens the file anew
+	_, err = LockPackages()
+	assert.ErrorIs(t, err, ferror.PackagesLocked)
+
+	assert.Nil(t, l.Unlock())
+	l, err = LockPackages()
+	assert.Nil(t, err)
+	assert.Nil(t, l.Unlock())
+}
@@ -30,25 +30,48 @@ func ReadLines(fileName string) ([]string, error) {
 }
 
 // ReadLinesWithComments reads file using ReadLines, but ignores commented-out
-// lines starting with "#".
+// lines starting with "#" and comments at the end of the lines. Lines ending
+// with a backslash are joined with the next one, as in the requirements files
+// of pip.
 func ReadLinesWithComments(fileName string) ([]string, error) {
 	var result []string
+	var continuation string
 	rawLines, err := ReadLines(fileName)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, line := range rawLines {
-		if !strings.HasPrefix(line, "#") {
+		// The comment must be preceded by whitespace, otherwise it can be a
+		// part of URL
+		if strings.HasPrefix(line, "#") {
+			line = ""
+		} else if i := strings.Index(line, " #"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			continuation += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+
+		line = strings.TrimSpace(continuation + line)
+		continuation = ""
+		if line != "" {
 			result = append(result, line)
 		}
 	}
 
+	if continuation = strings.TrimSpace(continuation); continuation != "" {
+		// The last line ends with a backslash
+		result = append(result, continuation)
+	}
+
 	return result, nil
 }
 
 // CreateInstallerFile creates a file named "INSTALLER" (PEP 627) in the
-// specified path and writes the "fext" name there.
+// specified path and writes the "fext" name there. The file is not executable.
 func CreateInstallerFile(path string) error {
 	// https://peps.python.org/pep-0627/#optional-installer-file
 	f, err := os.Create(filepath.Join(path, "INSTALLER"))
@@ -56,11 +79,6 @@ func CreateInstallerFile(path string) error {
 		return err
 	}
 
-	err = f.Chmod(config.DefaultChmod)
-	if err != nil {
-		return err
-	}
-
 	_, err = f.WriteString("fext\n")
 	if err != nil {
 		return err
@@ -0,0 +1,73 @@
+package io
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+// getLauncherSource returns the python code of the script that imports the
+// object from the module, calls it and exits with its result.
+func getLauncherSource(module, object string) string {
+	importName, _, _ := strings.Cut(object, ".")
+	return fmt.Sprintf(`# -*- coding: utf-8 -*-
+import re
+import sys
+from %s import %s
+if __name__ == "__main__":
+    sys.argv[0] = re.sub(r"(-script\.pyw?|\.exe|\.cmd)?$", "", sys.argv[0])
+    sys.exit(%s())
+`, module, importName, object)
+}
+
+// writeScript creates the file with the given content in the directory, which
+// is created if it doesn't exist. The file is made executable if requested.
+func writeScript(path, content string, executable bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return err
+	}
+
+	mode := fileMode
+	if executable {
+		mode = executableMode
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// PrepareScript makes the script from the data directory of the wheel
+// executable. If the script starts with "#!python", its first line is
+// replaced with the shebang of config.PythonExecPath
+// (https://packaging.python.org/en/latest/specifications/binary-distribution-format/#recommended-installer-features).
+func PrepareScript(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(data, []byte("#!python")) {
+		var rest []byte
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			rest = data[i+1:]
+		}
+		data = append([]byte(getShebang(config.PythonExecPath)), rest...)
+		if err = os.WriteFile(path, data, executableMode); err != nil {
+			return err
+		}
+	}
+
+	return os.Chmod(path, executableMode&^config.Umask)
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package io
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+// maxShebangLength is the maximum length of the shebang line the kernel reads.
+const maxShebangLength = 127
+
+// getShebang returns the first lines of the script that make the system run it
+// with the interpreter. If the path to the interpreter can't be written in the
+// shebang line, the script is started by the shell, which runs the
+// interpreter, and the python code sees these lines as a string.
+func getShebang(interpreter string) string {
+	if !filepath.IsAbs(interpreter) {
+		return "#!/usr/bin/env " + interpreter + "\n"
+	} else if strings.ContainsAny(interpreter, " \t") || len(interpreter)+2 > maxShebangLength {
+		return "#!/bin/sh\n'''exec' \"" + interpreter + "\" \"$0\" \"$@\"\n' '''\n"
+	}
+
+	return "#!" + interpreter + "\n"
+}
+
+// WriteLauncher writes the executable script with the given name to the
+// directory. The script runs the object of the module with
+// config.PythonExecPath. GUI scripts don't differ from the console ones on
+// Linux. Returns the paths to the created files.
+func WriteLauncher(dir, name, module, object string, gui bool) ([]string, error) {
+	path := filepath.Join(dir, name)
+	content := getShebang(config.PythonExecPath) + getLauncherSource(module, object)
+
+	return []string{path}, writeScript(path, content, true)
+}
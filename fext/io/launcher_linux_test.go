@@ -0,0 +1,40 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+func TestGetShebang(t *testing.T) {
+	assert.Equal(t, "#!/usr/bin/python3.12\n", getShebang("/usr/bin/python3.12"))
+	assert.Equal(t, "#!/usr/bin/env python3\n", getShebang("python3"))
+	assert.Equal(
+		t,
+		"#!/bin/sh\n'''exec' \"/home/user/my env/bin/python\" \"$0\" \"$@\"\n' '''\n",
+		getShebang("/home/user/my env/bin/python"),
+	)
+	assert.True(t, strings.HasPrefix(getShebang("/"+strings.Repeat("a", maxShebangLength)), "#!/bin/sh\n"))
+}
+
+func TestWriteLauncher(t *testing.T) {
+	dir := t.TempDir()
+	files, err := WriteLauncher(dir, "tool", "tool.cli", "App.run", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "tool")}, files)
+
+	data, err := os.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "#!"+config.PythonExecPath+"\n") || !filepath.IsAbs(config.PythonExecPath))
+	assert.Contains(t, string(data), "from tool.cli import App\n")
+	assert.Contains(t, string(data), "sys.exit(App.run())\n")
+
+	info, err := os.Stat(files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, executableMode&^config.Umask, info.Mode().Perm())
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package io
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+// getShebang returns the first line of the script that makes the Python
+// launcher run it with the interpreter.
+func getShebang(interpreter string) string {
+	if strings.ContainsAny(interpreter, " \t") {
+		return "#!\"" + interpreter + "\"\n"
+	}
+
+	return "#!" + interpreter + "\n"
+}
+
+// getGUIInterpreter returns the path to the interpreter that runs the scripts
+// without opening the console window.
+func getGUIInterpreter(interpreter string) string {
+	if strings.HasSuffix(strings.ToLower(interpreter), "python.exe") {
+		return interpreter[:len(interpreter)-4] + "w.exe"
+	}
+
+	return interpreter + "w"
+}
+
+// WriteLauncher writes the script with the given name to the directory, along
+// with the batch file that runs it with config.PythonExecPath. GUI scripts are
+// run without the console window. Returns the paths to the created files.
+func WriteLauncher(dir, name, module, object string, gui bool) ([]string, error) {
+	scriptName, interpreter, command := name+"-script.py", config.PythonExecPath, "@"
+	if gui {
+		scriptName, interpreter, command = name+"-script.pyw", getGUIInterpreter(interpreter), "@start \"\" "
+	}
+
+	scriptPath := filepath.Join(dir, scriptName)
+	if err := writeScript(scriptPath, getLauncherSource(module, object), false); err != nil {
+		return nil, err
+	}
+
+	batchPath := filepath.Join(dir, name+".cmd")
+	content := command + "\"" + interpreter + "\" \"%~dp0" + scriptName + "\" %*\r\n"
+	if err := writeScript(batchPath, content, false); err != nil {
+		return nil, err
+	}
+
+	return []string{scriptPath, batchPath}, nil
+}
@@ -0,0 +1,38 @@
+package io
+
+import "os"
+
+// FileLock is an exclusive lock held on the file by the process. The system
+// releases the lock when the process exits, even if it is killed, so the lock
+// of a dead process never blocks the others.
+type FileLock struct {
+	f *os.File
+}
+
+// TryLockFile opens the file, creating it if necessary, and locks it without
+// waiting. Returns false if the file is locked by another process.
+func TryLockFile(fileName string) (*FileLock, bool, error) {
+	f, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+
+	locked, err := lockFile(f)
+	if err != nil || !locked {
+		_ = f.Close()
+		return nil, false, err
+	}
+
+	return &FileLock{f: f}, true, nil
+}
+
+// Unlock releases the lock and closes the file. The file itself is kept, so
+// the other processes can lock it.
+func (l *FileLock) Unlock() error {
+	if err := unlockFile(l.f); err != nil {
+		_ = l.f.Close()
+		return err
+	}
+
+	return l.f.Close()
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package io
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile locks the file exclusively without waiting. Returns false if the
+// file is locked by another process.
+func lockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// unlockFile releases the lock of the file.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,107 @@
+package io
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// partialSuffix is appended to the path of the copy made by MovePath until it
+// is complete.
+const partialSuffix = ".fext-partial"
+
+// MovePath moves the file or directory to the destination path, which must
+// not exist. The path is renamed if possible. If the destination is located on
+// another filesystem, e.g. the scripts directory is mounted separately, the
+// path is copied next to the destination, renamed into place, and then
+// removed. So the destination never contains a partial copy, but a leftover
+// copy (see GetPartialPath) may remain if the process is killed.
+func MovePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	return moveByCopy(src, dst)
+}
+
+// GetPartialPath returns the path where MovePath copies the file or directory
+// before renaming it into the destination path.
+func GetPartialPath(dst string) string {
+	return dst + partialSuffix
+}
+
+// moveByCopy copies the file or directory to the partial path, renames it to
+// the destination path and removes the source.
+func moveByCopy(src, dst string) error {
+	partial := GetPartialPath(dst)
+	if err := os.RemoveAll(partial); err != nil {
+		return err
+	}
+
+	if err := copyPath(src, partial); err != nil {
+		_ = os.RemoveAll(partial)
+		return err
+	} else if err = os.Rename(partial, dst); err != nil {
+		_ = os.RemoveAll(partial)
+		return err
+	}
+
+	return os.RemoveAll(src)
+}
+
+// copyPath copies the file or directory recursively, keeping the permissions
+// of the files and the symbolic links as they are.
+func copyPath(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			if err = os.Mkdir(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+// copyFile copies the content of the regular file and sets the permissions
+// regardless of the process umask.
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	} else if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chmod(dst, mode)
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package io
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice checks if the rename failed because the paths are located on
+// different filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,45 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveByCopy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the file modes and the symbolic links are not copied on Windows")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	assert.Nil(t, os.MkdirAll(filepath.Join(src, "pkg"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "tool"), []byte("#!/bin/sh"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(src, "pkg", "module.py"), []byte("import os"), 0644))
+	assert.Nil(t, os.Symlink("tool", filepath.Join(src, "link")))
+
+	// The copy left by the killed process is replaced
+	dst := filepath.Join(dir, "dst")
+	assert.Nil(t, os.MkdirAll(GetPartialPath(dst), 0755))
+	assert.Nil(t, moveByCopy(src, dst))
+	assert.NoDirExists(t, src)
+	assert.NoDirExists(t, GetPartialPath(dst))
+
+	info, err := os.Stat(filepath.Join(dst, "tool"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	data, err := os.ReadFile(filepath.Join(dst, "pkg", "module.py"))
+	assert.Nil(t, err)
+	assert.Equal(t, "import os", string(data))
+	link, err := os.Readlink(filepath.Join(dst, "link"))
+	assert.Nil(t, err)
+	assert.Equal(t, "tool", link)
+
+	// The destination is never replaced
+	assert.Nil(t, os.Mkdir(src, 0755))
+	assert.NotNil(t, moveByCopy(src, dst))
+	assert.DirExists(t, src)
+	assert.NoDirExists(t, GetPartialPath(dst))
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package io
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isCrossDevice checks if the rename failed because the paths are located on
+// different volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// NewHash returns a new hash for the algorithm named the same way as in the
+// Python "hashlib". Only the algorithms of the SHA-2 family are supported, the
+// other ones (md5, sha1) are considered insecure.
+// Returns ferror.UnsupportedHash if the algorithm is not supported.
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha224":
+		return sha256.New224(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, &ferror.UnsupportedHash{Algorithm: algorithm}
+	}
+}
+
+// getHashSize returns the digest size of the hash in the pip format, which
+// reflects the strength of the algorithm. Returns 0 if the hash is empty or its
+// algorithm is not supported.
+func getHashSize(hash string) int {
+	algorithm, _, _ := strings.Cut(hash, ":")
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return 0
+	}
+
+	return h.Size()
+}
+
+// parseHashFragment separates the hash algorithm and the file digest from the
+// URL fragment (PEP 503), e.g. "...whl#sha256=<digest>". Returns two empty
+// strings if the link has no fragment.
+// Returns ferror.SyntaxError if the fragment is invalid.
+func parseHashFragment(link string) (string, string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", "", err
+	} else if u.Fragment == "" {
+		return "", "", nil
+	}
+
+	algorithm, digest, found := strings.Cut(u.Fragment, "=")
+	if !found || algorithm == "" || digest == "" {
+		return "", "", ferror.SyntaxError
+	}
+
+	return algorithm, strings.ToLower(digest), nil
+}
+
+// ParseHash separates the hash algorithm and the digest from the hash in the
+// pip format, e.g. "sha256:<digest>".
+// Returns ferror.SyntaxError if the hash is invalid, or ferror.UnsupportedHash
+// if the algorithm is not supported.
+func ParseHash(s string) (string, string, error) {
+	algorithm, digest, found := strings.Cut(s, ":")
+	if !found || algorithm == "" || digest == "" {
+		return "", "", ferror.SyntaxError
+	} else if _, err := NewHash(algorithm); err != nil {
+		return "", "", err
+	}
+
+	return algorithm, strings.ToLower(digest), nil
+}
+
+// VerifyFile computes the file digest and checks that it matches one of the
+// allowed hashes in the pip format, e.g. "sha256:<digest>". The digest is
+// computed once for each algorithm.
+// Returns ferror.HashMismatch if none of the hashes match.
+func VerifyFile(filePath string, hashes []string) error {
+	digests := map[string]string{}
+
+	for _, s := range hashes {
+		algorithm, digest, err := ParseHash(s)
+		if err != nil {
+			return err
+		}
+
+		actual, ok := digests[algorithm]
+		if !ok {
+			actual, err = ComputeDigest(filePath, algorithm)
+			if err != nil {
+				return err
+			}
+			digests[algorithm] = actual
+		}
+
+		if actual == digest {
+			return nil
+		}
+	}
+
+	var actual []string
+	for algorithm, digest := range digests {
+		actual = append(actual, algorithm+":"+digest)
+	}
+	sort.Strings(actual)
+
+	return &ferror.HashMismatch{
+		File:     filepath.Base(filePath),
+		Expected: strings.Join(hashes, ", "),
+		Actual:   strings.Join(actual, ", "),
+	}
+}
+
+// ComputeDigest reads the file and returns its hex digest computed with the
+// algorithm. Returns ferror.UnsupportedHash if the algorithm is not supported.
+func ComputeDigest(filePath, algorithm string) (string, error) {
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// ParseWheelFileName returns the name and the version of the package from the
+// wheel file name, e.g. "pkg-1.0-py3-none-any.whl".
+// Returns ferror.SyntaxError if the file name is not a wheel name.
+func ParseWheelFileName(fileName string) (string, string, error) {
+	if !strings.HasSuffix(fileName, ".whl") || len(strings.Split(fileName, "-")) < 5 {
+		return "", "", ferror.SyntaxError
+	}
+
+	tags := parsePackageTags(fileName)
+	return tags.name, tags.version, nil
+}
+
+// GetLinkFileName returns the name of the file the URL points to. The query
+// and the fragment of the URL are ignored.
+func GetLinkFileName(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return path.Base(u.Path)
+}
+
+// StripFragment returns the URL without the fragment, e.g. the file digest.
+func StripFragment(link string) string {
+	link, _, _ = strings.Cut(link, "#")
+	return link
+}
+
+// GetLocalPath returns the local path the "file://" URL points to. Returns
+// false if the URL refers to a remote file.
+func GetLocalPath(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+
+	p := u.Path
+	if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		// The path with the Windows drive letter, e.g. "/C:/pkg.whl"
+		p = p[1:]
+	}
+
+	return filepath.FromSlash(p), true
+}
+
+// NewFileURL returns the "file://" URL pointing to the local file.
+// Returns an error if the absolute path to the file can't be determined.
+func NewFileURL(filePath string) (string, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	return (&url.URL{Scheme: "file", Path: p}).String(), nil
+}
+
+// RedactURL returns the URL with the password replaced by "xxxxx", so it can
+// be displayed safely.
+func RedactURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	return u.Redacted()
+}
+
+// StripCredentials returns the URL without the user information, so it can be
+// stored safely.
+func StripCredentials(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.User == nil {
+		return link
+	}
+
+	u.User = nil
+	return u.String()
+}
+
+// AddCredentials returns the URL with the user information of the first index
+// URL located on the same host, so the files stored without the credentials
+// can be downloaded from the authenticated index. The URL is returned as is if
+// it already has the user information, or there is no such index.
+func AddCredentials(link string, indexURLs []string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.User != nil {
+		return link
+	}
+
+	for _, indexURL := range indexURLs {
+		index, err := url.Parse(indexURL)
+		if err == nil && index.User != nil && index.Scheme == u.Scheme && index.Host == u.Host {
+			u.User = index.User
+			return u.String()
+		}
+	}
+
+	return link
+}
@@ -3,41 +3,67 @@
 package web
 
 import (
+	"errors"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/fextpkg/cli/fext/config"
-	"github.com/fextpkg/cli/fext/expression"
 )
 
-func checkCompatibility(platformTag string) (bool, error) {
-	// There can be several platforms and they alternate through a point
-	platforms := strings.Split(platformTag, ".")
-
-	for _, platform := range platforms {
-		// Select only manylinux with the glibc version,
-		// since the rest are legacy
-		// https://github.com/pypa/manylinux
-		if strings.HasPrefix(platform, "manylinux_") {
-			res, err := parseAndCompare(platform)
-			if err != nil {
-				return false, err
-			} else if res {
-				return true, nil
-			}
-		}
-	}
+// legacyManylinuxTags are the manylinux tags defined before PEP 600, by the
+// glibc version they are equal to.
+var legacyManylinuxTags = map[string]string{
+	"2_17": "manylinux2014",
+	"2_12": "manylinux2010",
+	"2_5":  "manylinux1",
+}
 
-	return false, nil
+// platformTags returns the platform tags supported by the system, from the
+// most specific to the least one.
+func platformTags() []string {
+	return getLinuxTags(config.GLibCVersion, config.MarkerArch)
 }
 
-// parseAndCompare parses and compares the architecture of the platform, and also
-// checks the glibc version to ensure that it is >= to the current version.
-func parseAndCompare(platform string) (bool, error) {
-	data := strings.SplitN(platform, "_", 4) // ["manylinux", glibc_major, glibc_minor, arch]
-	if config.MarkerArch != data[3] {
-		return false, nil
+// getLinuxTags returns the platform tags of the glibc version and the
+// architecture: the manylinux tags from the glibc version down to the oldest
+// one defined for the architecture, and the generic linux tag at the end.
+// The legacy manylinux tags follow their modern equivalents.
+// https://github.com/pypa/manylinux
+func getLinuxTags(glibcVersion, arch string) []string {
+	var tags []string
+
+	major, minor, _ := strings.Cut(glibcVersion, ".")
+	glibcMajor, err := strconv.Atoi(major)
+	if err != nil {
+		return []string{"linux_" + arch}
+	}
+	glibcMinor, err := strconv.Atoi(minor)
+	if err != nil {
+		return []string{"linux_" + arch}
+	}
+
+	// The manylinux tags older than manylinux2014 are defined for the x86
+	// architectures only
+	oldestMinor := 17
+	if arch == "x86_64" || arch == "i686" {
+		oldestMinor = 5
+	}
+
+	for m := glibcMinor; glibcMajor == 2 && m >= oldestMinor; m-- {
+		version := "2_" + strconv.Itoa(m)
+		tags = append(tags, "manylinux_"+version+"_"+arch)
+		if legacy, ok := legacyManylinuxTags[version]; ok {
+			tags = append(tags, legacy+"_"+arch)
+		}
 	}
 
-	version := strings.Join(data[1:3], ".")
-	return expression.CompareVersion(config.GLibCVersion, ">=", version)
+	return append(tags, "linux_"+arch)
+}
+
+// isProcessAlive checks if the process with the given ID is running. The
+// process owned by another user is considered running too.
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
 }
@@ -0,0 +1,27 @@
+//go:build linux
+
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLinuxTags(t *testing.T) {
+	assert.Equal(t, []string{
+		"manylinux_2_18_aarch64",
+		"manylinux_2_17_aarch64", "manylinux2014_aarch64",
+		"linux_aarch64",
+	}, getLinuxTags("2.18", "aarch64"))
+
+	assert.Equal(t, []string{
+		"manylinux_2_6_x86_64",
+		"manylinux_2_5_x86_64", "manylinux1_x86_64",
+		"linux_x86_64",
+	}, getLinuxTags("2.6", "x86_64"))
+
+	// The glibc is older than any manylinux tag of the architecture
+	assert.Equal(t, []string{"linux_ppc64le"}, getLinuxTags("2.12", "ppc64le"))
+	assert.Equal(t, []string{"linux_x86_64"}, getLinuxTags("unknown", "x86_64"))
+}
@@ -1,22 +1,31 @@
 package web
 
 import (
+	"encoding/hex"
 	"errors"
+	"hash"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
-	"golang.org/x/net/html"
-
 	"github.com/fextpkg/cli/fext/config"
 	"github.com/fextpkg/cli/fext/expression"
 	"github.com/fextpkg/cli/fext/ferror"
 )
 
+// DefaultIndexURL is the URL of the PyPi repository in the Simple API format
+// (https://peps.python.org/pep-0503/)
+const DefaultIndexURL = "https://pypi.org/simple/"
+
 type PyPiRequest struct {
+	// URL of the repository in the Simple API format
+	indexURL string
 	// Input package name
 	pkgName string
 	// Input conditions
@@ -45,46 +54,123 @@ type packageTags struct {
 	platformTag string
 }
 
-// GetPackageData gets a first package version that fits the conditions of the
-// operators and system requirements. Returns package version, download link. An
+// Candidate is a distribution file found in the repository that is compatible
+// with the current system.
+type Candidate struct {
+	// Name of the distribution file
+	FileName string
+	// Package version
+	Version string
+	// Download link of the distribution file
+	Link string
+	// Digest of the distribution file provided by the repository, in the pip
+	// format, e.g. "sha256:<digest>". It is empty if the digest is unknown
+	Hash string
+	// Determines if the file was yanked from the repository (PEP 592)
+	Yanked bool
+	// Reason of yanking, it may be empty even if the file is yanked
+	YankedReason string
+	// Determines if the repository serves the core metadata of the file
+	// separately (PEP 658)
+	HasMetadata bool
+	// Digests of the core metadata file, by the hash algorithm name. It is
+	// empty if the digests are unknown
+	MetadataHashes map[string]string
+	// Upload time of the file in the ISO 8601 format, empty if unknown
+	UploadTime string
+}
+
+// GetCandidates gets all package versions that fit the conditions of the
+// operators and system requirements. Candidates are sorted from the newest
+// version to the oldest one, and each version is represented only once. An
 // error will be returned if a suitable version was not found or another error
 // occurred
-func (req *PyPiRequest) GetPackageData() (string, string, error) {
-	doc, err := req.getPackageList()
+func (req *PyPiRequest) GetCandidates() ([]*Candidate, error) {
+	files, err := req.getPackageList()
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return req.selectSuitableVersion(doc)
+	return req.selectSuitableVersions(files)
 }
 
-// DownloadPackage downloads the package file from PyPi repository.
-// Returns a path to downloaded file
+// DownloadPackage downloads the package file from PyPi repository into the
+// config.DownloadPath. If the link contains the file digest, it is computed while the file is being
+// downloaded and compared with the expected one. The partially downloaded or
+// mismatched file is removed.
+// Returns a path to downloaded file, ferror.HashMismatch if the digests are
+// different, or ferror.UnsupportedHash if the hash algorithm is not supported
 func (req *PyPiRequest) DownloadPackage(link string) (string, error) {
-	hashSum := strings.Split(link, "sha256=")[1]
+	algorithm, digest, err := parseHashFragment(link)
+	if err != nil {
+		return "", err
+	}
+
+	var h hash.Hash
+	if algorithm != "" {
+		// Check the algorithm before downloading anything
+		if h, err = NewHash(algorithm); err != nil {
+			return "", err
+		}
+	}
 
 	resp, err := http.Get(link)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", errors.New(strings.ToLower(resp.Status[4:]))
+	}
 
-	tmpFile, err := os.Create(filepath.Join(config.PythonLibPath, hashSum+".tmp"))
+	// The file is downloaded outside config.PythonLibPath, so it can never be
+	// mistaken for a part of the installed packages
+	if err = os.MkdirAll(config.DownloadPath, config.DefaultChmod); err != nil {
+		return "", err
+	}
+	tmpFile, err := os.CreateTemp(config.DownloadPath, downloadPrefix+strconv.Itoa(os.Getpid())+"-*.tmp")
 	if err != nil {
 		return "", err
 	}
 	defer tmpFile.Close()
 
-	if _, err = io.Copy(tmpFile, io.Reader(resp.Body)); err != nil {
+	var w io.Writer = tmpFile
+	if h != nil {
+		// Compute the digest while downloading to avoid reading the file
+		// once again
+		w = io.MultiWriter(tmpFile, h)
+	}
+
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		removeFile(tmpFile)
 		return "", err
 	}
 
+	if h != nil {
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+			removeFile(tmpFile)
+			return "", &ferror.HashMismatch{
+				File:     path.Base(resp.Request.URL.Path),
+				Expected: algorithm + ":" + digest,
+				Actual:   algorithm + ":" + actual,
+			}
+		}
+	}
+
 	return tmpFile.Name(), nil
 }
 
-// getPackageList gets a web page with the package list
-func (req *PyPiRequest) getPackageList() (*html.Node, error) {
-	resp, err := http.Get("https://pypi.org/simple/" + req.pkgName + "/")
+// Maximum size of the core metadata file served by the repository, in bytes.
+// Real files are a few kilobytes, the limit protects from misbehaving servers
+var maxMetadataSize int64 = 16 << 20
+
+// GetMetadata downloads the core metadata of the distribution file, which the
+// repository serves separately from the file itself (PEP 658), so the
+// dependencies can be learned without downloading the whole file. If the
+// repository provides the digests of the metadata, it is verified.
+// Returns ferror.HashMismatch if the digests are different.
+func (req *PyPiRequest) GetMetadata(c *Candidate) ([]byte, error) {
+	resp, err := http.Get(StripFragment(c.Link) + ".metadata")
 	if err != nil {
 		return nil, err
 	}
@@ -93,67 +179,280 @@ func (req *PyPiRequest) getPackageList() (*html.Node, error) {
 		return nil, errors.New(strings.ToLower(resp.Status[4:]))
 	}
 
-	doc, err := html.Parse(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize+1))
 	if err != nil {
 		return nil, err
+	} else if int64(len(data)) > maxMetadataSize {
+		return nil, errors.New("metadata file is too large")
 	}
 
-	return doc, nil
+	if expected := selectHash(c.MetadataHashes); expected != "" {
+		algorithm, digest, _ := strings.Cut(expected, ":")
+		h, _ := NewHash(algorithm)
+		h.Write(data)
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+			return nil, &ferror.HashMismatch{
+				File:     c.FileName + ".metadata",
+				Expected: expected,
+				Actual:   algorithm + ":" + actual,
+			}
+		}
+	}
+
+	return data, nil
 }
 
-// Parse document and select a correct version. Returns version, download link
-func (req *PyPiRequest) selectSuitableVersion(doc *html.Node) (string, string, error) {
-	// html => body (on pypi)
-	startNode := doc.FirstChild.NextSibling.FirstChild.NextSibling.NextSibling.LastChild
+// downloadPrefix is the prefix of the temporary files of the downloads. The
+// files are named "fext-<pid>-<random>.tmp", so the files of the running
+// processes can be told apart from the ones left by the dead processes.
+const downloadPrefix = "fext-"
+
+// RemoveStrayDownloads removes the temporary files left by the downloads that
+// were interrupted. Only the files of the processes that are not running
+// anymore are removed, so the concurrent downloads are not affected.
+func RemoveStrayDownloads() error {
+	files, err := filepath.Glob(filepath.Join(config.DownloadPath, downloadPrefix+"*-*.tmp"))
+	if err != nil {
+		return err
+	}
 
-	// Check the latest versions first
-	for node := startNode; node != nil; node = node.PrevSibling {
-		// Elements with package data are stored in the "a" tag.
-		// Exclude all other tags, like "br" and others.
-		if node.Data != "a" {
+	for _, fileName := range files {
+		pid, _, _ := strings.Cut(strings.TrimPrefix(filepath.Base(fileName), downloadPrefix), "-")
+		owner, err := strconv.Atoi(pid)
+		if err != nil || owner == os.Getpid() || isProcessAlive(owner) {
 			continue
 		}
 
-		version, link, err := req.getPackageInfo(node)
+		if err = os.RemoveAll(fileName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeFile closes and removes the file. Errors are ignored, since the file
+// is removed only when another error has occurred.
+func removeFile(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// getPackageList gets the project page with the package files from the
+// repository. The JSON format of the page is requested, but the HTML one is
+// accepted too. Links are resolved against the final URL of the page.
+// Returns ferror.PackageNotFound if the repository doesn't know the package.
+func (req *PyPiRequest) getPackageList() ([]*indexFile, error) {
+	r, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(req.indexURL, "/")+"/"+req.pkgName+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Accept", acceptHeader)
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &ferror.PackageNotFound{Package: req.pkgName, Index: RedactURL(req.indexURL)}
+	} else if resp.StatusCode != 200 {
+		return nil, errors.New(strings.ToLower(resp.Status[4:]))
+	}
+
+	if isJSONPage(resp.Header.Get("Content-Type")) {
+		return parseJSONPage(resp.Body, resp.Request.URL)
+	}
+
+	return parseHTMLPage(resp.Body, resp.Request.URL)
+}
+
+// Select all correct versions among the files of the project page. Returns
+// candidates sorted from the newest version to the oldest one. If there are
+// several suitable files of the same version, the best one is selected
+// according to the tag priority and the build tag, regardless of the page
+// order
+func (req *PyPiRequest) selectSuitableVersions(files []*indexFile) ([]*Candidate, error) {
+	var candidates []*Candidate
+
+	for _, f := range files {
+		version, err := req.checkFile(f.fileName, f.requiresPython)
 		if err != nil {
 			// Critical error, it is impossible to continue the search
-			return "", "", err
+			return nil, err
 		} else if version == "" {
-			// A suitable version was not found, continue the search
+			// A suitable version was not found. Continue the search
 			continue
-		} else {
-			// A suitable version is found
-			return version, link, nil
 		}
+
+		c := &Candidate{
+			FileName:       f.fileName,
+			Version:        version,
+			Link:           f.link,
+			Hash:           selectHash(f.hashes),
+			Yanked:         f.yanked,
+			YankedReason:   f.yankedReason,
+			HasMetadata:    f.hasMetadata,
+			MetadataHashes: f.metadataHashes,
+			UploadTime:     f.uploadTime,
+		}
+		algorithm, digest, _ := parseHashFragment(c.Link)
+		if fragment := algorithm + ":" + digest; getHashSize(fragment) > getHashSize(c.Hash) {
+			// The fragment has the only or the strongest supported digest
+			c.Hash = fragment
+		} else if c.Hash != "" {
+			// The digest is passed in the fragment, so the file is verified
+			// while downloading
+			c.Link = StripFragment(c.Link) + "#" + strings.Replace(c.Hash, ":", "=", 1)
+		}
+		candidates = append(candidates, c)
+	}
+
+	candidates = sortCandidates(candidates)
+	if len(candidates) == 0 {
+		return nil, ferror.NoSuitableVersion
 	}
 
-	return "", "", ferror.NoSuitableVersion
+	return candidates, nil
 }
 
-// getPackageInfo parses the node and checks all the data from it for
-// compliance with the desired version. If successful, it returns the version and
-// downloads link. If the version could not be found, empty strings will be
-// returned without an error. If an error occurred, it will be returned with
-// empty strings.
-func (req *PyPiRequest) getPackageInfo(node *html.Node) (string, string, error) {
-	fullData := node.FirstChild.Data
+// GetLocalCandidates looks for the wheels of the package in the local
+// directories, e.g. a wheelhouse prepared for the machines without network
+// access. The wheels are filtered the same way as the files in the
+// repository, and their links are "file://" URLs. Candidates are sorted from
+// the newest version to the oldest one, and each version is represented only
+// once. Returns ferror.NoSuitableVersion if a suitable version was not found,
+// or an error if the directory can't be read.
+func (req *PyPiRequest) GetLocalCandidates(dirs []string) ([]*Candidate, error) {
+	var candidates []*Candidate
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
 
-	// Select only wheel package
-	if !strings.HasSuffix(fullData, ".whl") {
-		return "", "", nil
+		for _, entry := range entries {
+			fileName := entry.Name()
+			name, _, err := ParseWheelFileName(fileName)
+			if entry.IsDir() || err != nil || expression.NormalizeName(name) != expression.NormalizeName(req.pkgName) {
+				continue
+			}
+
+			// The wheel file doesn't tell the required Python version, the
+			// compatibility tags are checked only
+			version, err := req.checkFile(fileName, "")
+			if err != nil {
+				return nil, err
+			} else if version == "" {
+				continue
+			}
+
+			link, err := NewFileURL(filepath.Join(dir, fileName))
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, &Candidate{
+				FileName: fileName,
+				Version:  version,
+				Link:     link,
+			})
+		}
 	}
 
-	pkgTags := parsePackageTags(fullData)
+	candidates = sortCandidates(candidates)
+	if len(candidates) == 0 {
+		return nil, ferror.NoSuitableVersion
+	}
 
-	// Check Python compatibility tags
-	ok, err := pkgTags.CheckCompatibility()
-	if !ok {
-		return "", "", err
+	return candidates, nil
+}
+
+// MergeCandidates combines the candidates found in several places. If the
+// same version is found several times, the candidate from the earlier list is
+// kept. The result is sorted from the newest version to the oldest one.
+func MergeCandidates(lists ...[]*Candidate) []*Candidate {
+	var candidates []*Candidate
+	for _, list := range lists {
+		candidates = append(candidates, list...)
+	}
+
+	unique := uniqueVersions(candidates)
+	sort.SliceStable(unique, func(i, j int) bool {
+		newer, _ := expression.CompareVersion(unique[i].Version, ">", unique[j].Version)
+		return newer
+	})
+
+	return unique
+}
+
+// sortCandidates ranks the candidates by the version first, from the newest
+// one to the oldest one, then by the priority of the compatibility tags, and
+// then by the build tag, from the highest one. Only the best candidate of each
+// version is kept.
+func sortCandidates(candidates []*Candidate) []*Candidate {
+	// The files are usually listed in ascending order, but it's not guaranteed
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return isPreferred(candidates[i], candidates[j])
+	})
+
+	return uniqueVersions(candidates)
+}
+
+// isPreferred checks if the candidate a ranks higher than the candidate b.
+func isPreferred(a, b *Candidate) bool {
+	if newer, _ := expression.CompareVersion(a.Version, ">", b.Version); newer {
+		return true
+	} else if older, _ := expression.CompareVersion(a.Version, "<", b.Version); older {
+		return false
+	}
+
+	priorityA, priorityB := GetTagPriority(a.FileName), GetTagPriority(b.FileName)
+	if priorityA != priorityB {
+		// Unsupported tags are ranked last
+		return priorityB == -1 || priorityA != -1 && priorityA < priorityB
+	}
+
+	return compareBuildTags(parsePackageTags(a.FileName).buildTag, parsePackageTags(b.FileName).buildTag) > 0
+}
+
+// uniqueVersions keeps only the first candidate of each version.
+func uniqueVersions(candidates []*Candidate) []*Candidate {
+	var unique []*Candidate
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if !seen[c.Version] {
+			seen[c.Version] = true
+			unique = append(unique, c)
+		}
+	}
+
+	return unique
+}
+
+// checkFile checks the distribution file name and the Python versions
+// required by the file for compliance with the desired version. If
+// successful, it returns the package version. If the file doesn't fit, an
+// empty string will be returned without an error.
+func (req *PyPiRequest) checkFile(fileName, versionRequirements string) (string, error) {
+	// Select only wheel package
+	if !strings.HasSuffix(fileName, ".whl") || len(strings.Split(fileName, "-")) < 5 {
+		return "", nil
 	}
 
-	// Check package version
-	ok, err = expression.CompareConditions(pkgTags.version, req.conditions)
-	if !ok {
+	// Check Python compatibility tags
+	if !IsCompatible(fileName) {
+		return "", nil
+	}
+	pkgTags := parsePackageTags(fileName)
+
+	// Check package version. The version is validated separately, since there
+	// may be no conditions to compare with
+	var ok bool
+	err := expression.ValidateVersion(pkgTags.version)
+	if err == nil {
+		ok, err = expression.CompareConditions(pkgTags.version, req.conditions)
+	}
+	if !ok || err != nil {
 		if errors.Is(err, strconv.ErrSyntax) {
 			// Due to the very strange description and lack of compatibility
 			// with semantic versioning in PEP 440, we don't have an elegant
@@ -162,83 +461,77 @@ func (req *PyPiRequest) getPackageInfo(node *html.Node) (string, string, error)
 			// to parsing these two parts. Since it is undesirable to interrupt
 			// the package downloading, we ignore this case. These two
 			// additional parts of the version are not highly significant.
-			return "", "", nil
+			return "", nil
 		}
-		return "", "", err
+		return "", err
 	}
 
-	link, versionRequirements := parseAttrs(node.Attr)
-	_, conditions := expression.ParseConditions(versionRequirements)
-
-	// Check the Python version
-	ok, err = expression.CompareConditions(config.PythonVersion, conditions)
-	if !ok {
-		return "", "", err
+	// Check the Python version. The file with malformed requirements can't
+	// be checked, so it is skipped instead of interrupting the search
+	ok, err = expression.CompareConditions(config.PythonVersion, parseRequiresPython(versionRequirements))
+	if !ok || err != nil {
+		return "", nil
 	}
 
-	return pkgTags.version, link, nil
+	return pkgTags.version, nil
 }
 
-// checkPythonCompatibility accepts python-tag of a package (PEP 425) and checks
-// compatibility with installed python. Currently, only "py" and "cp" tags are
-// implemented. When any other tag is passed, false will be returned.
-func (tag *packageTags) checkPythonCompatibility() bool {
-	// https://packaging.python.org/en/latest/specifications/platform-compatibility-tags/#python-tag
-
-	// There can be several versions and they alternate through a point
-	for _, version := range strings.Split(tag.pyTag, ".") {
-		code := version[:2]
-
-		if code == "py" {
-			// Since there is support for versions 2 and 3 at once, we only check for the
-			// presence of the number 3
-			if version[2:] == "3" {
-				return true
-			}
-		} else if code == "cp" {
-			cpythonVersion := config.GetPythonMinorVersion()
-			// Remove the extra characters and compare only the minor version
-			tagVersion := version[3:]
-
-			if tagVersion == "" || tagVersion == cpythonVersion {
-				return true
-			}
-		}
-	}
-
-	return false
+// IsCompatible checks if the wheel with the given file name can be installed
+// on the current system, i.e. any of its compatibility tags is supported by
+// the interpreter. Files that are not wheels are never compatible.
+func IsCompatible(fileName string) bool {
+	return GetTagPriority(fileName) != -1
 }
 
-// checkPlatformCompatibility accepts platform-tag of a package (PEP 425) and checks
-// them for compatibility with the current platform
-func (tag *packageTags) checkPlatformCompatibility() (bool, error) {
-	// https://packaging.python.org/en/latest/specifications/platform-compatibility-tags/#platform-tag
-	if tag.platformTag == "any" {
-		return true, nil
+// GetWheelTags returns the compatibility tags of the wheel with the given file
+// name. Compressed tag sets are expanded, e.g. "py2.py3-none-any" becomes
+// "py2-none-any" and "py3-none-any". Returns nil if the file is not a wheel.
+func GetWheelTags(fileName string) []string {
+	if !strings.HasSuffix(fileName, ".whl") || len(strings.Split(fileName, "-")) < 5 {
+		return nil
 	}
-	return checkCompatibility(tag.platformTag)
-}
 
-// CheckCompatibility calls other tag checking methods and returns the final
-// result.
-func (tag *packageTags) CheckCompatibility() (bool, error) {
-	ok, err := tag.checkPlatformCompatibility()
-	if err != nil || !ok {
-		return false, err
+	tag := parsePackageTags(fileName)
+	var tags []string
+	for _, pyTag := range strings.Split(tag.pyTag, ".") {
+		for _, abiTag := range strings.Split(tag.abiTag, ".") {
+			for _, platformTag := range strings.Split(tag.platformTag, ".") {
+				tags = append(tags, pyTag+"-"+abiTag+"-"+platformTag)
+			}
+		}
 	}
 
-	return tag.checkPythonCompatibility(), nil
+	return tags
 }
 
 // NewRequest creates a new package search query object on PyPi with the
 // specified conditions
 func NewRequest(pkgName string, cond []expression.Condition) *PyPiRequest {
+	return NewIndexRequest(DefaultIndexURL, pkgName, cond)
+}
+
+// NewIndexRequest creates a new package search query object on the repository
+// with the given Simple API URL, e.g. a private index.
+func NewIndexRequest(indexURL, pkgName string, cond []expression.Condition) *PyPiRequest {
 	return &PyPiRequest{
+		indexURL:   indexURL,
 		pkgName:    pkgName,
 		conditions: cond,
 	}
 }
 
+// ValidateIndexURL checks that the repository URL can be used to look up the
+// packages, i.e. it is an absolute HTTP(S) URL.
+// Returns ferror.InvalidIndexURL otherwise.
+func ValidateIndexURL(indexURL string) error {
+	u, err := url.Parse(indexURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ferror.InvalidIndexURL{URL: indexURL}
+	}
+
+	return nil
+}
+
 // parsePackageTags separates all package tags and creates a new structure
 // packageTags with them.
 func parsePackageTags(s string) *packageTags {
@@ -265,18 +558,3 @@ func parsePackageTags(s string) *packageTags {
 
 	return pkgTags
 }
-
-// parseAttrs parses the HTML element attributes and return download link,
-// python requirement versions. Example: ("https://...", ">=3.7")
-func parseAttrs(attrs []html.Attribute) (string, string) {
-	var link, versionRequirements string
-	for _, attr := range attrs {
-		switch attr.Key {
-		case "href":
-			link = attr.Val
-		case "data-requires-python":
-			versionRequirements = strings.ReplaceAll(attr.Val, ",", "")
-		}
-	}
-	return link, versionRequirements
-}
@@ -0,0 +1,276 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// Content types of the project page of the Simple API
+// (https://peps.python.org/pep-0691/). The JSON format is preferred, the HTML
+// one is requested with the lower quality, since some indexes support only it
+const (
+	contentTypeJSON = "application/vnd.pypi.simple.v1+json"
+	contentTypeHTML = "application/vnd.pypi.simple.v1+html"
+
+	acceptHeader = contentTypeJSON + ", " + contentTypeHTML + ";q=0.2, text/html;q=0.01"
+)
+
+// indexFile is a distribution file listed on the project page of the index.
+type indexFile struct {
+	// Name of the distribution file
+	fileName string
+	// Absolute download link of the file
+	link string
+	// Digests of the file, by the hash algorithm name
+	hashes map[string]string
+	// Python versions required by the file, e.g. ">=3.8"
+	requiresPython string
+	// Determines if the file was yanked from the index (PEP 592)
+	yanked bool
+	// Reason of yanking, it may be empty even if the file is yanked
+	yankedReason string
+	// Determines if the core metadata of the file is served separately
+	// (PEP 658)
+	hasMetadata bool
+	// Digests of the core metadata file, by the hash algorithm name
+	metadataHashes map[string]string
+	// Upload time in the ISO 8601 format, empty if unknown
+	uploadTime string
+}
+
+// simpleProject is the project page in the JSON format.
+type simpleProject struct {
+	Meta struct {
+		APIVersion string `json:"api-version"`
+	} `json:"meta"`
+	Files []struct {
+		FileName       string            `json:"filename"`
+		URL            string            `json:"url"`
+		Hashes         map[string]string `json:"hashes"`
+		RequiresPython string            `json:"requires-python"`
+		Yanked         json.RawMessage   `json:"yanked"`
+		// The name of the key was changed by PEP 714, older indexes
+		// still use the original one
+		CoreMetadata     json.RawMessage `json:"core-metadata"`
+		DistInfoMetadata json.RawMessage `json:"dist-info-metadata"`
+		UploadTime       string          `json:"upload-time"`
+	} `json:"files"`
+}
+
+// isJSONPage checks if the project page returned by the index is in the JSON
+// format according to its content type.
+func isJSONPage(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == contentTypeJSON
+}
+
+// parseJSONPage parses the project page in the JSON format and returns the
+// files in the order they are listed. Links are resolved against the base URL
+// of the page.
+// Returns ferror.UnsupportedAPIVersion if the major version of the API is
+// unknown.
+func parseJSONPage(r io.Reader, base *url.URL) ([]*indexFile, error) {
+	var project simpleProject
+	if err := json.NewDecoder(r).Decode(&project); err != nil {
+		return nil, err
+	}
+
+	if major, _, _ := strings.Cut(project.Meta.APIVersion, "."); major != "1" {
+		return nil, &ferror.UnsupportedAPIVersion{Version: project.Meta.APIVersion}
+	}
+
+	files := make([]*indexFile, 0, len(project.Files))
+	for _, f := range project.Files {
+		ref, err := base.Parse(f.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		file := &indexFile{
+			fileName:       f.FileName,
+			link:           ref.String(),
+			hashes:         f.Hashes,
+			requiresPython: f.RequiresPython,
+			uploadTime:     f.UploadTime,
+		}
+
+		// The values are either booleans, or a string and a dictionary
+		// respectively
+		var yanked interface{}
+		if len(f.Yanked) > 0 {
+			if err = json.Unmarshal(f.Yanked, &yanked); err != nil {
+				return nil, err
+			}
+		}
+		switch v := yanked.(type) {
+		case bool:
+			file.yanked = v
+		case string:
+			file.yanked, file.yankedReason = true, v
+		}
+
+		metadata := f.CoreMetadata
+		if len(metadata) == 0 {
+			metadata = f.DistInfoMetadata
+		}
+		if len(metadata) > 0 && string(metadata) != "false" && string(metadata) != "null" {
+			file.hasMetadata = true
+			// The value "true" doesn't provide the hashes
+			_ = json.Unmarshal(metadata, &file.metadataHashes)
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// parseHTMLPage parses the project page in the HTML format (PEP 503) and
+// returns the files in the order they are listed. Anchors are collected
+// wherever they are located in the document, so the pages of any index
+// layout are supported, including plain directory listings. Links are
+// resolved against the base URL of the page, which the "base" element may
+// override.
+func parseHTMLPage(r io.Reader, base *url.URL) ([]*indexFile, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var anchors []*html.Node
+	var baseHref string
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			if node.Data == "a" {
+				anchors = append(anchors, node)
+			} else if href, ok := getAttr(node, "href"); ok && node.Data == "base" && baseHref == "" {
+				// Only the first base element is taken into account
+				baseHref = href
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	if baseHref != "" {
+		if base, err = base.Parse(baseHref); err != nil {
+			return nil, err
+		}
+	}
+
+	var files []*indexFile
+	for _, node := range anchors {
+		file := parseAttrs(node.Attr)
+		if file.link == "" {
+			continue
+		}
+
+		ref, err := base.Parse(file.link)
+		if err != nil {
+			// The malformed link can't be downloaded anyway
+			continue
+		}
+		file.link = ref.String()
+		// The text of the anchor is the file name too, but directory
+		// listings may truncate it
+		file.fileName = path.Base(ref.Path)
+
+		if algorithm, digest, _ := parseHashFragment(file.link); algorithm != "" {
+			file.hashes = map[string]string{algorithm: digest}
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// getAttr returns the value of the element attribute. Returns false if the
+// element doesn't have the attribute.
+func getAttr(node *html.Node, key string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+
+	return "", false
+}
+
+// parseAttrs parses the HTML element attributes and returns the file with
+// the download link, the required Python versions, the yanked status and the
+// metadata availability filled in.
+func parseAttrs(attrs []html.Attribute) *indexFile {
+	file := &indexFile{}
+	for _, attr := range attrs {
+		switch attr.Key {
+		case "href":
+			file.link = attr.Val
+		case "data-requires-python":
+			// The parser unescapes the value once, but some indexes escape
+			// it twice, e.g. "&amp;gt;=3.8"
+			file.requiresPython = html.UnescapeString(attr.Val)
+		case "data-yanked":
+			file.yanked, file.yankedReason = true, html.UnescapeString(attr.Val)
+		case "data-core-metadata", "data-dist-info-metadata":
+			// The name of the attribute was changed by PEP 714, older
+			// indexes still use the original one
+			if attr.Val == "false" || file.hasMetadata {
+				continue
+			}
+			file.hasMetadata = true
+			if algorithm, digest, found := strings.Cut(attr.Val, "="); found {
+				file.metadataHashes = map[string]string{algorithm: digest}
+			}
+		}
+	}
+
+	return file
+}
+
+// parseRequiresPython parses the Python version specifiers required by the
+// file (PEP 440), e.g. ">=3.8, !=3.9.*". Specifiers are separated with
+// commas, and the whitespace between them is insignificant.
+func parseRequiresPython(s string) []expression.Condition {
+	var conditions []expression.Condition
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.Join(strings.Fields(spec), "")
+		i := strings.IndexFunc(spec, func(r rune) bool {
+			return !strings.ContainsRune("~=!<>", r)
+		})
+		if i == -1 {
+			// The specifier is empty or has no version
+			continue
+		}
+
+		conditions = append(conditions, expression.Condition{Operator: spec[:i], Value: spec[i:]})
+	}
+
+	return conditions
+}
+
+// selectHash returns the digest of the file computed with a supported
+// algorithm, in the pip format, e.g. "sha256:<digest>". SHA-256 is preferred,
+// since it is the one stored in the lock files. Returns an empty string if
+// there is no such digest.
+func selectHash(hashes map[string]string) string {
+	for _, algorithm := range []string{"sha256", "sha512", "sha384", "sha224"} {
+		if digest, ok := hashes[algorithm]; ok && digest != "" {
+			return algorithm + ":" + strings.ToLower(digest)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+var (
+	tagPrioritiesOnce sync.Once
+	// Priorities of the supported tags, by the tag, the lower is the better
+	tagPriorities map[string]int
+)
+
+// getSupportedTags returns the compatibility tags (PEP 425) supported by the
+// interpreter, from the most preferred to the least one, in the same order as
+// packaging.tags.sys_tags() does for CPython:
+//  1. the tags of the interpreter ABI, e.g. "cp312-cp312-<platform>";
+//  2. the stable ABI of the current and older versions, e.g. "cp312-abi3";
+//  3. the tags without ABI, e.g. "cp312-none-<platform>", "py3-none-<platform>";
+//  4. the pure Python tags, e.g. "cp312-none-any", "py3-none-any".
+//
+// Within each group, the platform tags are taken from the most specific to
+// the least one.
+func getSupportedTags() []string {
+	minor, err := strconv.Atoi(config.GetPythonMinorVersion())
+	if err != nil {
+		return nil
+	}
+
+	interpreter := "cp3" + strconv.Itoa(minor)
+	abi := interpreter
+	if minor < 8 {
+		// Python 3.7 and older are built with pymalloc by default
+		abi += "m"
+	}
+
+	platforms := platformTags()
+	var tags []string
+	addTags := func(pyTag, abiTag string, platforms []string) {
+		for _, platform := range platforms {
+			tags = append(tags, pyTag+"-"+abiTag+"-"+platform)
+		}
+	}
+
+	addTags(interpreter, abi, platforms)
+	addTags(interpreter, "abi3", platforms)
+	addTags(interpreter, "none", platforms)
+	for m := minor - 1; m > 1; m-- {
+		addTags("cp3"+strconv.Itoa(m), "abi3", platforms)
+	}
+
+	// The generic Python tags: the current version, the major one and the
+	// older versions
+	pyTags := []string{"py3" + strconv.Itoa(minor), "py3"}
+	for m := minor - 1; m >= 0; m-- {
+		pyTags = append(pyTags, "py3"+strconv.Itoa(m))
+	}
+
+	for _, pyTag := range pyTags {
+		addTags(pyTag, "none", platforms)
+	}
+	addTags(interpreter, "none", []string{"any"})
+	for _, pyTag := range pyTags {
+		addTags(pyTag, "none", []string{"any"})
+	}
+
+	return tags
+}
+
+// GetTagPriority returns the priority of the best tag of the wheel with the
+// given file name, the lower is the better. Returns -1 if none of the wheel
+// tags are supported, or the file is not a wheel.
+func GetTagPriority(fileName string) int {
+	tagPrioritiesOnce.Do(func() {
+		tagPriorities = map[string]int{}
+		for n, tag := range getSupportedTags() {
+			tagPriorities[tag] = n
+		}
+	})
+
+	priority := -1
+	for _, tag := range GetWheelTags(fileName) {
+		if n, ok := tagPriorities[tag]; ok && (priority == -1 || n < priority) {
+			priority = n
+		}
+	}
+
+	return priority
+}
+
+// compareBuildTags compares the optional build tags of the wheels (PEP 427).
+// The tags are compared by the leading number first, and by the rest of the
+// tag next, the missing tag is the lowest. Returns a positive number if the
+// first tag is greater, a negative one if it is less, or zero if they are
+// equal.
+func compareBuildTags(a, b string) int {
+	splitTag := func(tag string) (int, string) {
+		i := strings.IndexFunc(tag, func(r rune) bool { return r < '0' || r > '9' })
+		if i == -1 {
+			i = len(tag)
+		}
+		number, _ := strconv.Atoi(tag[:i])
+		return number, tag[i:]
+	}
+
+	if a == "" || b == "" {
+		return len(a) - len(b)
+	}
+
+	numberA, restA := splitTag(a)
+	numberB, restB := splitTag(b)
+	if numberA != numberB {
+		return numberA - numberB
+	}
+
+	return strings.Compare(restA, restB)
+}
@@ -2,8 +2,38 @@
 
 package web
 
-import "github.com/fextpkg/cli/fext/config"
+import (
+	"errors"
 
-func checkCompatibility(platformTag string) (bool, error) {
-	return platformTag == config.PlatformTag, nil
+	"golang.org/x/sys/windows"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+// stillActive is the exit code of the process that is running.
+const stillActive = 259
+
+// platformTags returns the platform tags supported by the system.
+func platformTags() []string {
+	return []string{config.PlatformTag}
+}
+
+// isProcessAlive checks if the process with the given ID is running. The
+// process that can't be queried is considered running.
+func isProcessAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+		// There is no process with this ID
+		return false
+	} else if err != nil {
+		return true
+	}
+	defer windows.CloseHandle(h)
+
+	var code uint32
+	if err = windows.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+
+	return code == stillActive
 }
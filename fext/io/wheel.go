@@ -0,0 +1,318 @@
+package io
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io/web"
+)
+
+// wheelMajorVersion is the major version of the wheel format supported by
+// fext.
+const wheelMajorVersion = 1
+
+// wheelMinorVersion is the latest minor version of the wheel format known to
+// fext. Wheels of newer minor versions are installed with a warning.
+const wheelMinorVersion = 0
+
+// WheelInfo is the content of the WHEEL file of the archive
+// (https://packaging.python.org/en/latest/specifications/binary-distribution-format/#the-dist-info-directory).
+type WheelInfo struct {
+	// Version of the wheel format, e.g. "1.0"
+	Version string
+	// Expanded compatibility tags of the wheel, e.g. "py3-none-any"
+	Tags []string
+	// Optional build number
+	Build string
+}
+
+// ReadWheelInfo reads the WHEEL file from the meta-directory of the wheel
+// archive without extracting it.
+// Returns ferror.MalformedWheelFile if the file is missing, or the required
+// fields have invalid values.
+func ReadWheelInfo(path string) (*WheelInfo, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f := findMetaFile(r.File, "WHEEL")
+	if f == nil {
+		return nil, &ferror.MalformedWheelFile{Field: "WHEEL"}
+	}
+	data, err := readEntry(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWheelInfo(data)
+}
+
+// parseWheelInfo processes the content of the WHEEL file, which has the same
+// format as the METADATA file. Root-Is-Purelib is only validated, since
+// purelib and platlib are the same directory in fext.
+// Returns ferror.MalformedWheelFile if the required fields are missing or
+// have invalid values.
+func parseWheelInfo(data []byte) (*WheelInfo, error) {
+	var info WheelInfo
+	var purelib string
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Wheel-Version":
+			info.Version = value
+		case "Root-Is-Purelib":
+			purelib = strings.ToLower(value)
+		case "Tag":
+			info.Tags = append(info.Tags, value)
+		case "Build":
+			info.Build = value
+		}
+	}
+
+	if _, _, err := info.getVersion(); err != nil {
+		return nil, err
+	}
+
+	if purelib != "true" && purelib != "false" {
+		return nil, &ferror.MalformedWheelFile{Field: "Root-Is-Purelib"}
+	}
+
+	return &info, nil
+}
+
+// getVersion returns the major and the minor versions of the wheel format.
+// Returns ferror.MalformedWheelFile if the version is invalid.
+func (info *WheelInfo) getVersion() (int, int, error) {
+	major, minor, found := strings.Cut(info.Version, ".")
+	majorVersion, err := strconv.Atoi(major)
+	if err != nil || !found {
+		return 0, 0, &ferror.MalformedWheelFile{Field: "Wheel-Version"}
+	}
+
+	minorVersion, err := strconv.Atoi(minor)
+	if err != nil {
+		return 0, 0, &ferror.MalformedWheelFile{Field: "Wheel-Version"}
+	}
+
+	return majorVersion, minorVersion, nil
+}
+
+// CheckVersion checks if fext can install the wheel of this format version.
+// Returns true if the minor version is newer than the known one, so the wheel
+// may use the features fext doesn't understand, or
+// ferror.UnsupportedWheelVersion if the major version is unknown.
+func (info *WheelInfo) CheckVersion() (bool, error) {
+	major, minor, err := info.getVersion()
+	if err != nil {
+		return false, err
+	} else if major != wheelMajorVersion {
+		return false, &ferror.UnsupportedWheelVersion{Version: info.Version}
+	}
+
+	return minor > wheelMinorVersion, nil
+}
+
+// CheckTags checks that the tags declared in the WHEEL file are the same as
+// the tags of the wheel file name. Wheels that don't declare any tags are not
+// checked.
+// Returns ferror.WheelTagMismatch with the first tag found only on one side.
+func (info *WheelInfo) CheckTags(fileTags []string) error {
+	if len(info.Tags) == 0 {
+		return nil
+	}
+
+	declared := map[string]bool{}
+	for _, tag := range info.Tags {
+		declared[tag] = true
+	}
+
+	for _, tag := range fileTags {
+		if !declared[tag] {
+			return &ferror.WheelTagMismatch{Tag: tag}
+		}
+		delete(declared, tag)
+	}
+	for _, tag := range info.Tags {
+		if declared[tag] {
+			return &ferror.WheelTagMismatch{Tag: tag}
+		}
+	}
+
+	return nil
+}
+
+// isRecordFile checks if the archive entry is the RECORD file or its
+// signature, which are not listed in the RECORD file.
+func isRecordFile(name string) bool {
+	switch path.Base(name) {
+	case "RECORD", "RECORD.jws", "RECORD.p7s":
+		dirName, _, _ := strings.Cut(name, "/")
+		return path.Dir(name) == dirName && strings.HasSuffix(dirName, ".dist-info")
+	}
+
+	return false
+}
+
+// VerifyRecord checks that the files of the wheel archive match its RECORD
+// file before anything is extracted. Each file must be listed in the RECORD
+// with the same digest and size, and each listed file must be present in the
+// archive.
+// Returns ferror.RecordMismatch with the path of the first mismatched file,
+// or ferror.UnsupportedHash if the digest is computed with an unknown or
+// insecure algorithm.
+func VerifyRecord(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f := findMetaFile(r.File, "RECORD")
+	if f == nil {
+		return &ferror.RecordMismatch{Path: "RECORD"}
+	}
+	record, err := readRecord(f)
+	if err != nil {
+		return err
+	}
+
+	for _, f = range r.File {
+		if strings.HasSuffix(f.Name, "/") || isRecordFile(f.Name) {
+			continue
+		}
+
+		row, ok := record[f.Name]
+		if !ok {
+			return &ferror.RecordMismatch{Path: f.Name}
+		}
+		delete(record, f.Name)
+
+		if err = verifyEntry(f, row[0], row[1]); err != nil {
+			return err
+		}
+	}
+
+	for name, row := range record {
+		// The RECORD file itself is listed without the digest
+		if row[0] != "" {
+			return &ferror.RecordMismatch{Path: name}
+		}
+	}
+
+	return nil
+}
+
+// MatchesRecord checks if the files of the wheel archive are installed
+// unchanged, i.e. the digests listed in the RECORD file of the archive are the
+// same as the installed ones, given by the path. The files of the data
+// directory are skipped, since they are moved or rewritten on installation.
+// The archive must match its RECORD, see VerifyRecord.
+// Returns false if there are no files to compare.
+func MatchesRecord(path string, installed map[string]string) (bool, error) {
+	if err := VerifyRecord(path); err != nil {
+		return false, err
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	record, err := readRecord(findMetaFile(r.File, "RECORD"))
+	if err != nil {
+		return false, err
+	}
+
+	var compared int
+	for name, row := range record {
+		dirName, _, _ := strings.Cut(name, "/")
+		if row[0] == "" || strings.HasSuffix(dirName, ".data") {
+			continue
+		} else if installed[name] != row[0] {
+			return false, nil
+		}
+		compared++
+	}
+
+	return compared > 0, nil
+}
+
+// readRecord reads the RECORD file of the archive and returns the digests and
+// the sizes of the files, by the path.
+func readRecord(f *zip.File) (map[string][2]string, error) {
+	data, err := readEntry(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	// Some tools omit the trailing empty fields
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	record := map[string][2]string{}
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+
+		var value [2]string
+		copy(value[:], row[1:])
+		record[row[0]] = value
+	}
+
+	return record, nil
+}
+
+// verifyEntry computes the digest of the archive entry and compares it and the
+// entry size with the ones listed in the RECORD file. The digest has the form
+// "<algorithm>=<urlsafe-base64>".
+// Returns ferror.RecordMismatch if they are different.
+func verifyEntry(f *zip.File, digest, size string) error {
+	algorithm, expected, found := strings.Cut(digest, "=")
+	if !found {
+		return &ferror.RecordMismatch{Path: f.Name}
+	}
+
+	h, err := web.NewHash(algorithm)
+	if err != nil {
+		return err
+	}
+
+	rf, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
+
+	if _, err = io.Copy(h, rf); err != nil {
+		return err
+	}
+
+	actual := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+	if actual != strings.TrimRight(expected, "=") {
+		return &ferror.RecordMismatch{Path: f.Name}
+	} else if size != "" && size != strconv.FormatUint(f.UncompressedSize64, 10) {
+		return &ferror.RecordMismatch{Path: f.Name}
+	}
+
+	return nil
+}
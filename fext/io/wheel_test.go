@@ -0,0 +1,114 @@
+package io
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// recordLine returns the RECORD line describing the file with the content.
+func recordLine(name string, content []byte) string {
+	digest := sha256.Sum256(content)
+	return name + ",sha256=" + base64.RawURLEncoding.EncodeToString(digest[:]) + "," + strconv.Itoa(len(content)) + "\n"
+}
+
+func TestReadWheelInfo(t *testing.T) {
+	path := createArchive(t, zipEntry{
+		name: "pkg-1.0.dist-info/WHEEL",
+		content: []byte("Wheel-Version: 1.0\nGenerator: bdist_wheel (0.42.0)\nRoot-Is-Purelib: true\n" +
+			"Tag: py2-none-any\nTag: py3-none-any\n"),
+	})
+
+	info, err := ReadWheelInfo(path)
+	assert.Nil(t, err)
+	assert.Equal(t, &WheelInfo{
+		Version: "1.0",
+		Tags:    []string{"py2-none-any", "py3-none-any"},
+	}, info)
+
+	newer, err := info.CheckVersion()
+	assert.Nil(t, err)
+	assert.False(t, newer)
+
+	assert.Nil(t, info.CheckTags([]string{"py3-none-any", "py2-none-any"}))
+	assert.Equal(t, &ferror.WheelTagMismatch{Tag: "py2-none-any"}, info.CheckTags([]string{"py3-none-any"}))
+	assert.Equal(
+		t,
+		&ferror.WheelTagMismatch{Tag: "cp312-cp312-linux_x86_64"},
+		info.CheckTags([]string{"py2-none-any", "py3-none-any", "cp312-cp312-linux_x86_64"}),
+	)
+}
+
+func TestReadWheelInfo_Malformed(t *testing.T) {
+	_, err := ReadWheelInfo(createArchive(t, zipEntry{name: "pkg-1.0.dist-info/METADATA"}))
+	assert.Equal(t, &ferror.MalformedWheelFile{Field: "WHEEL"}, err)
+
+	for content, field := range map[string]string{
+		"Root-Is-Purelib: true\n":                        "Wheel-Version",
+		"Wheel-Version: one\nRoot-Is-Purelib: true\n":    "Wheel-Version",
+		"Wheel-Version: 1.0\n":                           "Root-Is-Purelib",
+		"Wheel-Version: 1.0\nRoot-Is-Purelib: maybe\n":   "Root-Is-Purelib",
+		"Wheel-Version: 1.x\nRoot-Is-Purelib: false\n":   "Wheel-Version",
+		"Wheel-Version: 1.0\r\nRoot-Is-Purelib: yes\r\n": "Root-Is-Purelib",
+	} {
+		_, err = parseWheelInfo([]byte(content))
+		assert.Equal(t, &ferror.MalformedWheelFile{Field: field}, err, content)
+	}
+}
+
+func TestWheelInfo_CheckVersion(t *testing.T) {
+	newer, err := (&WheelInfo{Version: "1.9"}).CheckVersion()
+	assert.Nil(t, err)
+	assert.True(t, newer)
+
+	_, err = (&WheelInfo{Version: "2.0"}).CheckVersion()
+	assert.Equal(t, &ferror.UnsupportedWheelVersion{Version: "2.0"}, err)
+}
+
+func TestVerifyRecord(t *testing.T) {
+	module := []byte("import os")
+	metadata := []byte("Name: pkg")
+	record := recordLine("pkg/__init__.py", module) + recordLine("pkg-1.0.dist-info/METADATA", metadata) +
+		"pkg-1.0.dist-info/RECORD,,\n"
+
+	assert.Nil(t, VerifyRecord(createArchive(
+		t,
+		zipEntry{name: "pkg/"},
+		zipEntry{name: "pkg/__init__.py", content: module},
+		zipEntry{name: "pkg-1.0.dist-info/METADATA", content: metadata},
+		zipEntry{name: "pkg-1.0.dist-info/RECORD", content: []byte(record)},
+	)))
+
+	for name, entries := range map[string][]zipEntry{
+		"pkg/__init__.py": {
+			{name: "pkg/__init__.py", content: []byte("import sys")},
+			{name: "pkg-1.0.dist-info/METADATA", content: metadata},
+		},
+		"pkg-1.0.dist-info/METADATA": {
+			{name: "pkg/__init__.py", content: module},
+		},
+		"pkg/extra.py": {
+			{name: "pkg/__init__.py", content: module},
+			{name: "pkg/extra.py"},
+			{name: "pkg-1.0.dist-info/METADATA", content: metadata},
+		},
+	} {
+		entries = append(entries, zipEntry{name: "pkg-1.0.dist-info/RECORD", content: []byte(record)})
+		assert.Equal(t, &ferror.RecordMismatch{Path: name}, VerifyRecord(createArchive(t, entries...)))
+	}
+
+	err := VerifyRecord(createArchive(t, zipEntry{name: "pkg/__init__.py", content: module}))
+	assert.Equal(t, &ferror.RecordMismatch{Path: "RECORD"}, err)
+
+	err = VerifyRecord(createArchive(
+		t,
+		zipEntry{name: "pkg/__init__.py", content: module},
+		zipEntry{name: "pkg-1.0.dist-info/RECORD", content: []byte("pkg/__init__.py,md5=AAAA,9\n")},
+	))
+	assert.Equal(t, &ferror.UnsupportedHash{Algorithm: "md5"}, err)
+}
@@ -4,51 +4,230 @@ import (
 	"archive/zip"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
 )
 
-func unzip(path string) error {
+// Limits that protect from the decompression bombs. The compression ratio is
+// checked only for the large entries, since small files full of repeated data
+// may be compressed very well for legitimate reasons.
+var (
+	// Maximum total size of the extracted files, in bytes
+	maxExtractedSize uint64 = 8 << 30
+	// Maximum ratio of the uncompressed entry size to the compressed one
+	maxCompressionRatio uint64 = 200
+	// Minimum size of the entry whose compression ratio is checked, in bytes
+	compressionRatioThreshold uint64 = 1 << 20
+)
+
+// validateEntryName checks that the archive entry stays inside the target
+// directory when extracted, and returns its cleaned path with the forward
+// slashes.
+// Returns ferror.AbsolutePath if the path is absolute, or ferror.PathTraversal
+// if it points outside the target directory.
+func validateEntryName(name string) (string, error) {
+	// Backslashes are treated as separators on Windows, so they are
+	// normalized to detect the traversal on any system
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" ||
+		len(slashed) > 1 && slashed[1] == ':' {
+		return "", &ferror.AbsolutePath{Path: name}
+	}
+
+	cleaned := path.Clean(slashed)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", &ferror.PathTraversal{Path: name}
+	}
+
+	return cleaned, nil
+}
+
+// validateArchive checks all archive entries before anything is extracted, so
+// a malicious archive doesn't leave any files behind.
+// Returns ferror.AbsolutePath or ferror.PathTraversal if the entry points
+// outside the target directory, ferror.SymlinkEntry if the entry is a
+// symbolic link, ferror.DuplicateEntry if the path is used several times, or
+// ferror.ArchiveTooLarge and ferror.CompressionRatioTooHigh if the archive
+// looks like a decompression bomb.
+func validateArchive(files []*zip.File) error {
+	seen := map[string]bool{}
+	var totalSize uint64
+
+	for _, f := range files {
+		name, err := validateEntryName(f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.Mode()&os.ModeSymlink != 0 {
+			return &ferror.SymlinkEntry{Path: f.Name}
+		}
+
+		if seen[name] {
+			return &ferror.DuplicateEntry{Path: f.Name}
+		}
+		seen[name] = true
+
+		// The sizes from the headers are reliable, since the reader fails if
+		// the entry data doesn't match them
+		totalSize += f.UncompressedSize64
+		if totalSize > maxExtractedSize {
+			return &ferror.ArchiveTooLarge{
+				Size:  strconv.FormatUint(totalSize, 10),
+				Limit: strconv.FormatUint(maxExtractedSize, 10),
+			}
+		}
+
+		if f.UncompressedSize64 >= compressionRatioThreshold {
+			compressed := f.CompressedSize64
+			if compressed == 0 {
+				compressed = 1
+			}
+			if ratio := f.UncompressedSize64 / compressed; ratio > maxCompressionRatio {
+				return &ferror.CompressionRatioTooHigh{Path: f.Name, Ratio: strconv.FormatUint(ratio, 10)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Permissions of the extracted files before the umask is applied, the same as
+// pip uses. The executable bit is kept only if the archive sets it
+const (
+	dirMode        os.FileMode = 0777
+	fileMode       os.FileMode = 0666
+	executableMode os.FileMode = 0777
+)
+
+// getEntryMode returns the permissions of the extracted file based on the
+// Unix mode stored in the external attributes of the archive entry. The
+// process umask is applied to them by the system.
+func getEntryMode(f *zip.File) os.FileMode {
+	if f.Mode()&0111 != 0 {
+		return executableMode
+	}
+
+	return fileMode
+}
+
+// extractFile writes the content of the archive entry to the file.
+func extractFile(f *zip.File, fpath string) error {
+	if err := os.MkdirAll(filepath.Dir(fpath), dirMode); err != nil {
+		return err
+	}
+
+	rf, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rf.Close()
+
+	outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, getEntryMode(f))
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(outFile, rf); err != nil {
+		_ = outFile.Close()
+		return err
+	}
+
+	return outFile.Close()
+}
+
+func unzip(path, dir string) error {
 	r, err := zip.OpenReader(path)
 	if err != nil {
 		return err
 	}
 	defer r.Close()
-	path = filepath.Dir(path) // remove hash-sum part
+
+	if err = validateArchive(r.File); err != nil {
+		return err
+	}
 
 	for _, f := range r.File {
-		fpath := filepath.Join(path, f.Name)
+		name, _ := validateEntryName(f.Name)
+		fpath := filepath.Join(dir, filepath.FromSlash(name))
 
 		if f.FileInfo().IsDir() {
-			err := os.MkdirAll(fpath, config.DefaultChmod)
-			if err != nil {
-				return err
-			}
+			err = os.MkdirAll(fpath, dirMode)
 		} else {
-			if err := os.MkdirAll(filepath.Dir(fpath), config.DefaultChmod); err != nil {
-				return err
-			} else {
-				outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, config.DefaultChmod)
-				if err != nil {
-					return err
-				}
-
-				rf, err := f.Open()
-				if err != nil {
-					return err
-				}
-
-				_, err = io.Copy(outFile, rf)
-				if err != nil {
-					return err
-				}
-			}
+			err = extractFile(f, fpath)
+		}
+		if err != nil {
+			return err
 		}
 	}
+
 	return nil
 }
 
-func ExtractPackage(path string) error {
-	return unzip(path)
+// ExtractPackage extracts the wheel archive into the directory. The archive is
+// validated beforehand, see validateArchive.
+func ExtractPackage(path, dir string) error {
+	return unzip(path, dir)
+}
+
+// GetExpectedMode returns the permissions the installed file should have,
+// taking into account the process umask. The executable bit is expected only
+// if the file was installed executable, i.e. the wheel declared it so, or it
+// is a script.
+func GetExpectedMode(info os.FileInfo, executable bool) os.FileMode {
+	if info.IsDir() {
+		return dirMode &^ config.Umask
+	} else if executable {
+		return executableMode &^ config.Umask
+	}
+
+	return fileMode &^ config.Umask
+}
+
+// ReadPackageMetadata reads the METADATA file from the meta-directory of the
+// wheel archive without extracting it.
+// Returns ferror.PackageDirectoryMissing if the archive doesn't contain it.
+func ReadPackageMetadata(path string) ([]byte, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f := findMetaFile(r.File, "METADATA")
+	if f == nil {
+		return nil, ferror.PackageDirectoryMissing
+	}
+
+	return readEntry(f)
+}
+
+// findMetaFile returns the archive entry of the file in the meta-directory, or
+// nil if there is no such entry.
+func findMetaFile(files []*zip.File, name string) *zip.File {
+	for _, f := range files {
+		// The meta-directory is always located in the root of the archive
+		dirName, fileName, found := strings.Cut(f.Name, "/")
+		if found && fileName == name && strings.HasSuffix(dirName, ".dist-info") {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// readEntry reads the whole content of the archive entry.
+func readEntry(f *zip.File) ([]byte, error) {
+	rf, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rf.Close()
+
+	return io.ReadAll(rf)
 }
@@ -0,0 +1,150 @@
+package io
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+// zipEntry is an entry of the crafted archive.
+type zipEntry struct {
+	name    string
+	content []byte
+	mode    os.FileMode
+}
+
+// createArchive writes the entries to a new archive in the temporary
+// directory and returns the path to it.
+func createArchive(t *testing.T, entries ...zipEntry) string {
+	path := filepath.Join(t.TempDir(), "pkg-1.0-py3-none-any.whl")
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			h.SetMode(e.mode)
+		}
+		fw, err := w.CreateHeader(h)
+		assert.Nil(t, err)
+		_, err = fw.Write(e.content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+
+	return path
+}
+
+// assertNothingExtracted checks that the directory is empty or doesn't exist.
+func assertNothingExtracted(t *testing.T, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		assert.Empty(t, entries)
+	} else {
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+}
+
+func TestExtractPackage(t *testing.T) {
+	path := createArchive(
+		t,
+		zipEntry{name: "pkg/"},
+		zipEntry{name: "pkg/__init__.py", content: []byte("import os")},
+		zipEntry{name: "pkg-1.0.dist-info/METADATA", content: []byte("Name: pkg")},
+	)
+	dir := filepath.Join(t.TempDir(), "staging")
+
+	assert.Nil(t, ExtractPackage(path, dir))
+	data, err := os.ReadFile(filepath.Join(dir, "pkg", "__init__.py"))
+	assert.Nil(t, err)
+	assert.Equal(t, "import os", string(data))
+}
+
+func TestExtractPackage_Unsafe(t *testing.T) {
+	safe := zipEntry{name: "pkg/__init__.py", content: []byte("import os")}
+
+	var traversal *ferror.PathTraversal
+	var absolute *ferror.AbsolutePath
+	var symlink *ferror.SymlinkEntry
+	var duplicate *ferror.DuplicateEntry
+	var tooLarge *ferror.ArchiveTooLarge
+	var ratio *ferror.CompressionRatioTooHigh
+
+	cases := []struct {
+		entry  zipEntry
+		target interface{}
+	}{
+		{zipEntry{name: "../../.bashrc", content: []byte("rm -rf ~")}, &traversal},
+		{zipEntry{name: "pkg/../../outside.py"}, &traversal},
+		{zipEntry{name: "..\\outside.py"}, &traversal},
+		{zipEntry{name: "/etc/passwd"}, &absolute},
+		{zipEntry{name: "\\windows\\system.ini"}, &absolute},
+		{zipEntry{name: "C:/Windows/system.ini"}, &absolute},
+		{zipEntry{name: "pkg/link", content: []byte("/etc/passwd"), mode: os.ModeSymlink | 0777}, &symlink},
+		{zipEntry{name: "pkg/./__init__.py", content: []byte("import sys")}, &duplicate},
+		{zipEntry{name: "pkg/zeros.bin", content: make([]byte, 4<<20)}, &ratio},
+	}
+
+	for _, c := range cases {
+		path := createArchive(t, safe, c.entry)
+		dir := filepath.Join(t.TempDir(), "staging")
+
+		err := ExtractPackage(path, dir)
+		assert.ErrorAs(t, err, c.target, c.entry.name)
+		assertNothingExtracted(t, dir)
+	}
+
+	defer func(limit uint64) { maxExtractedSize = limit }(maxExtractedSize)
+	maxExtractedSize = 16
+	path := createArchive(t, safe, zipEntry{name: "pkg/data.txt", content: bytes.Repeat([]byte("data"), 4)})
+	dir := filepath.Join(t.TempDir(), "staging")
+	assert.ErrorAs(t, ExtractPackage(path, dir), &tooLarge)
+	assertNothingExtracted(t, dir)
+}
+
+func TestExtractPackage_Modes(t *testing.T) {
+	path := createArchive(
+		t,
+		zipEntry{name: "pkg/__init__.py", content: []byte("import os"), mode: 0755},
+		zipEntry{name: "pkg/data.txt", content: []byte("data")},
+		zipEntry{name: "pkg/tool", content: []byte("#!/bin/sh"), mode: 0755},
+		zipEntry{name: "pkg/module.py", content: []byte("import sys"), mode: 0644},
+	)
+	dir := filepath.Join(t.TempDir(), "staging")
+	assert.Nil(t, ExtractPackage(path, dir))
+
+	for name, expected := range map[string]os.FileMode{
+		"__init__.py": executableMode,
+		"data.txt":    fileMode,
+		"tool":        executableMode,
+		"module.py":   fileMode,
+	} {
+		info, err := os.Stat(filepath.Join(dir, "pkg", name))
+		assert.Nil(t, err)
+		assert.Equal(t, expected&^config.Umask, info.Mode().Perm(), name)
+	}
+}
+
+func TestGetExpectedMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "module.py")
+	assert.Nil(t, os.WriteFile(path, []byte("content"), 0755))
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	// The mode of the file itself doesn't matter
+	assert.Equal(t, fileMode&^config.Umask, GetExpectedMode(info, false))
+	assert.Equal(t, executableMode&^config.Umask, GetExpectedMode(info, true))
+
+	info, err = os.Stat(filepath.Dir(path))
+	assert.Nil(t, err)
+	assert.Equal(t, dirMode&^config.Umask, GetExpectedMode(info, false))
+}
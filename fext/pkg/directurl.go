@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// DirectURLFileName is the name of the file in the meta-directory that
+// describes the URL the package was installed from.
+const DirectURLFileName = "direct_url.json"
+
+// DirectURL is the content of the "direct_url.json" file (PEP 610). It is
+// written only for the packages installed from the direct references rather
+// than found in the index.
+type DirectURL struct {
+	// URL of the distribution file, without the credentials and the fragment
+	URL string `json:"url"`
+	// Present if the URL points to the distribution file
+	ArchiveInfo *ArchiveInfo `json:"archive_info,omitempty"`
+}
+
+// ArchiveInfo describes the distribution file the package was installed from.
+type ArchiveInfo struct {
+	// Digests of the file, by the hash algorithm name
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// GetDirectURL reads the "direct_url.json" file of the package. Returns nil
+// without an error if the package was not installed from a direct reference.
+func (p *Package) GetDirectURL() (*DirectURL, error) {
+	data, err := os.ReadFile(getAbsolutePath(p.metaDir, DirectURLFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var d DirectURL
+	if err = json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/ferror"
+	"github.com/fextpkg/cli/fext/io"
+)
+
+// EntryPointsFileName is the name of the file in the meta-directory that
+// lists the entry points of the package.
+const EntryPointsFileName = "entry_points.txt"
+
+// Groups of the entry points that define the executable scripts
+// (https://packaging.python.org/en/latest/specifications/entry-points/#use-for-scripts).
+const (
+	ConsoleScripts = "console_scripts"
+	GUIScripts     = "gui_scripts"
+)
+
+// EntryPoint is an object provided by the package for other code to use, e.g.
+// the function run by the executable script.
+type EntryPoint struct {
+	// Name of the group the entry point belongs to
+	Group string
+	// Name of the entry point. For scripts, it is the name of the executable
+	Name string
+	// Module to import, e.g. "black"
+	Module string
+	// Dotted path to the object inside the module, e.g. "patched_main". It may
+	// be empty if the entry point refers to the module itself
+	Object string
+}
+
+// IsScript checks if the entry point defines an executable script.
+func (e EntryPoint) IsScript() bool {
+	return e.Group == ConsoleScripts || e.Group == GUIScripts
+}
+
+// ReadEntryPoints reads the entry points from the file in the INI format
+// (https://packaging.python.org/en/latest/specifications/entry-points/#file-format).
+// The extra names following the object reference are ignored.
+// Returns ferror.UnexpectedEntryPoint if any entry point is malformed, or an
+// error if the file can't be read.
+func ReadEntryPoints(fileName string) ([]EntryPoint, error) {
+	lines, err := io.ReadLines(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entryPoints []EntryPoint
+	var group string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		} else if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		e, err := parseEntryPoint(group, line)
+		if err != nil {
+			return nil, err
+		}
+		entryPoints = append(entryPoints, e)
+	}
+
+	return entryPoints, nil
+}
+
+// parseEntryPoint parses the line in the form "name = module:object [extras]".
+// Returns ferror.UnexpectedEntryPoint if the line is malformed.
+func parseEntryPoint(group, line string) (EntryPoint, error) {
+	name, value, found := strings.Cut(line, "=")
+	if !found || group == "" {
+		return EntryPoint{}, &ferror.UnexpectedEntryPoint{EntryPoint: line}
+	}
+
+	if i := strings.Index(value, "["); i != -1 {
+		value = value[:i]
+	}
+	module, object, _ := strings.Cut(value, ":")
+
+	e := EntryPoint{
+		Group:  group,
+		Name:   strings.TrimSpace(name),
+		Module: strings.TrimSpace(module),
+		Object: strings.TrimSpace(object),
+	}
+	if e.Name == "" || e.Module == "" {
+		return EntryPoint{}, &ferror.UnexpectedEntryPoint{EntryPoint: line}
+	}
+
+	// The script name becomes a file name, and the object must be callable
+	if e.IsScript() && (e.Object == "" || !isValidScriptName(e.Name)) {
+		return EntryPoint{}, &ferror.UnexpectedEntryPoint{EntryPoint: line}
+	}
+
+	return e, nil
+}
+
+// isValidScriptName checks if the script name can be used as a file name in
+// the directory for scripts, without pointing outside of it.
+func isValidScriptName(name string) bool {
+	return name != "." && name != ".." && !strings.ContainsAny(name, `/\:`) && filepath.Base(name) == name
+}
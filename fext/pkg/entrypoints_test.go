@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/ferror"
+)
+
+func TestReadEntryPoints(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), EntryPointsFileName)
+	assert.Nil(t, os.WriteFile(fileName, []byte(`# comment
+[console_scripts]
+black = black:patched_main
+blackd = blackd:patched_main [d]
+
+[gui_scripts]
+viewer=viewer.app:App.run
+
+[pytest11]
+plugin = plugin.module
+`), 0644))
+
+	entryPoints, err := ReadEntryPoints(fileName)
+	assert.Nil(t, err)
+	assert.Equal(t, []EntryPoint{
+		{Group: ConsoleScripts, Name: "black", Module: "black", Object: "patched_main"},
+		{Group: ConsoleScripts, Name: "blackd", Module: "blackd", Object: "patched_main"},
+		{Group: GUIScripts, Name: "viewer", Module: "viewer.app", Object: "App.run"},
+		{Group: "pytest11", Name: "plugin", Module: "plugin.module"},
+	}, entryPoints)
+}
+
+func TestReadEntryPoints_Malformed(t *testing.T) {
+	for _, content := range []string{
+		"black = black:main",
+		"[console_scripts]\nblack",
+		"[console_scripts]\nblack = black",
+		"[console_scripts]\n../black = black:main",
+		"[gui_scripts]\n = black:main",
+	} {
+		fileName := filepath.Join(t.TempDir(), EntryPointsFileName)
+		assert.Nil(t, os.WriteFile(fileName, []byte(content), 0644))
+
+		_, err := ReadEntryPoints(fileName)
+		var unexpected *ferror.UnexpectedEntryPoint
+		assert.ErrorAs(t, err, &unexpected, content)
+	}
+}
@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"sort"
+
+	"github.com/fextpkg/cli/fext/expression"
+	"github.com/fextpkg/cli/fext/io"
+)
+
+// Graph is the dependency graph of the installed packages. Only the
+// dependencies required in the current environment are taken into account.
+// It is unknown which extras were requested, so the dependencies of all the
+// extras are followed, and an installed extra dependency is never taken for
+// an orphan.
+type Graph struct {
+	// Installed packages, by the normalized name
+	packages map[string]*Package
+	// Normalized names of the installed dependencies, by the package name
+	dependencies map[string][]string
+	// Normalized names of the installed packages that depend on the package,
+	// by its name
+	dependents map[string][]string
+	// Errors of the packages whose metadata can't be processed, by the name
+	// of the meta-directory
+	skipped map[string]error
+}
+
+// LoadGraph loads all the installed packages and builds the graph of their
+// dependencies. Dependencies that are not installed are skipped. The packages
+// whose metadata can't be processed are skipped too, they are listed by
+// Graph.GetSkipped.
+// Returns an error if the installed packages can't be listed.
+func LoadGraph() (*Graph, error) {
+	metaDirs, err := io.GetMetaDirectories()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{
+		packages:     map[string]*Package{},
+		dependencies: map[string][]string{},
+		dependents:   map[string][]string{},
+		skipped:      map[string]error{},
+	}
+	for _, metaDir := range metaDirs {
+		p, err := LoadFromMetaDir(metaDir)
+		if err != nil {
+			g.skipped[metaDir] = err
+			continue
+		}
+		g.packages[expression.NormalizeName(p.Name)] = p
+	}
+
+	for name, p := range g.packages {
+		depNames, err := p.GetDependencyNames()
+		if err != nil {
+			// The package can still be removed, but nothing is known about
+			// its dependencies
+			g.skipped[p.metaDir] = err
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, depName := range depNames {
+			if _, ok := g.packages[depName]; !ok || seen[depName] || depName == name {
+				continue
+			}
+			seen[depName] = true
+			g.dependencies[name] = append(g.dependencies[name], depName)
+			g.dependents[depName] = append(g.dependents[depName], name)
+		}
+	}
+
+	for _, names := range g.dependents {
+		sort.Strings(names)
+	}
+	for _, names := range g.dependencies {
+		sort.Strings(names)
+	}
+
+	return g, nil
+}
+
+// GetSkipped returns the errors of the packages whose metadata can't be
+// processed, by the name of the meta-directory. The dependencies of these
+// packages are not taken into account.
+func (g *Graph) GetSkipped() map[string]error {
+	return g.skipped
+}
+
+// Get returns the installed package, or nil if it is not installed.
+func (g *Graph) Get(pkgName string) *Package {
+	return g.packages[expression.NormalizeName(pkgName)]
+}
+
+// GetDependents returns the sorted normalized names of the installed packages
+// that depend on the package.
+func (g *Graph) GetDependents(pkgName string) []string {
+	return g.dependents[expression.NormalizeName(pkgName)]
+}
+
+// collectDependencies returns the normalized names of all the installed
+// packages the given ones depend on, directly or not. Each package is visited
+// once, so the cycles are handled.
+func (g *Graph) collectDependencies(names []string, skip map[string]bool) map[string]bool {
+	visited := map[string]bool{}
+	queue := append([]string{}, names...)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, dep := range g.dependencies[name] {
+			if !visited[dep] && !skip[dep] {
+				visited[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	return visited
+}
+
+// CollectRemovable returns the requested packages along with their
+// dependencies that no remaining package needs. A dependency is kept if it was
+// requested explicitly, or it is reachable from any package that is not
+// removed, so the dependencies forming a cycle are removed only together. The
+// names are normalized and sorted.
+// Returns the names of the packages to remove, and the kept dependencies with
+// the names of the remaining packages that depend on them.
+func (g *Graph) CollectRemovable(pkgNames []string) ([]string, map[string][]string) {
+	requested := map[string]bool{}
+	var names []string
+	for _, pkgName := range pkgNames {
+		name := expression.NormalizeName(pkgName)
+		if _, ok := g.packages[name]; ok && !requested[name] {
+			requested[name] = true
+			names = append(names, name)
+		}
+	}
+
+	candidates := g.collectDependencies(names, requested)
+
+	// The packages that are not affected by the removal, and the explicitly
+	// requested dependencies keep all the dependencies they reach, except the
+	// requested packages, which are removed anyway
+	var roots []string
+	for name, p := range g.packages {
+		if !requested[name] && (!candidates[name] || p.IsRequested()) {
+			roots = append(roots, name)
+		}
+	}
+	needed := g.collectDependencies(roots, requested)
+	for _, name := range roots {
+		needed[name] = true
+	}
+
+	removable := names
+	for name := range candidates {
+		if !needed[name] {
+			removable = append(removable, name)
+		}
+	}
+	sort.Strings(removable)
+
+	removed := map[string]bool{}
+	for _, name := range removable {
+		removed[name] = true
+	}
+
+	kept := map[string][]string{}
+	for name := range candidates {
+		if removed[name] {
+			continue
+		}
+		for _, dependent := range g.dependents[name] {
+			if !removed[dependent] {
+				kept[name] = append(kept[name], dependent)
+			}
+		}
+	}
+
+	return removable, kept
+}
+
+// CollectOrphans returns the packages that were installed as dependencies, but
+// no requested package needs them anymore. The names are normalized and
+// sorted.
+func (g *Graph) CollectOrphans() []string {
+	requested := map[string]bool{}
+	var roots []string
+	for name, p := range g.packages {
+		if p.IsRequested() {
+			requested[name] = true
+			roots = append(roots, name)
+		}
+	}
+	needed := g.collectDependencies(roots, requested)
+
+	var orphans []string
+	for name := range g.packages {
+		if !requested[name] && !needed[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+
+	return orphans
+}
@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+// writeInstalled creates the meta-directories of the installed packages with
+// the given dependencies in a temporary config.PythonLibPath.
+func writeInstalled(t *testing.T, packages map[string][]string) {
+	libPath := config.PythonLibPath
+	t.Cleanup(func() { config.PythonLibPath = libPath })
+	config.PythonLibPath = t.TempDir()
+
+	files := map[string]string{}
+	for name, dependencies := range packages {
+		metadata := "Name: " + name + "\nVersion: 1.0\n"
+		for _, dep := range dependencies {
+			metadata += "Requires-Dist: " + dep + "\n"
+			if _, extra, found := strings.Cut(dep, "extra == \""); found {
+				metadata += "Provides-Extra: " + strings.TrimSuffix(extra, "\"") + "\n"
+			}
+		}
+		files[name+"-1.0.dist-info/METADATA"] = metadata
+	}
+	writeTree(t, config.PythonLibPath, files)
+}
+
+func TestGraph_CollectRemovable(t *testing.T) {
+	writeInstalled(t, map[string][]string{
+		"app":       {"Requests (>=2.0)", "click", "missing"},
+		"tool":      {"click"},
+		"requests":  {"urllib3", "idna"},
+		"urllib3":   {},
+		"idna":      {},
+		"click":     {"colorama; sys_platform == \"win32\""},
+		"cycle_a":   {"cycle-b"},
+		"cycle-b":   {"cycle_a", "idna"},
+		"lonely":    {},
+		"colorama":  {},
+		"unrelated": {"pytest; extra == \"test\""},
+		"pytest":    {},
+	})
+	files := map[string]string{}
+	for _, name := range []string{"requests", "urllib3", "idna", "click", "cycle-b", "colorama", "pytest"} {
+		files[name+"-1.0.dist-info/"+DependencyFileName] = ""
+	}
+	writeTree(t, config.PythonLibPath, files)
+
+	g, err := LoadGraph()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app", "tool"}, g.GetDependents("Click"))
+
+	removable, kept := g.CollectRemovable([]string{"app", "not-installed"})
+	assert.Equal(t, []string{"app", "requests", "urllib3"}, removable)
+	assert.Equal(t, map[string][]string{
+		"click": {"tool"},
+		"idna":  {"cycle-b"},
+	}, kept)
+
+	// The dependencies forming a cycle are removed together
+	removable, kept = g.CollectRemovable([]string{"cycle-a"})
+	assert.Equal(t, []string{"cycle-a", "cycle-b"}, removable)
+	assert.Equal(t, map[string][]string{"idna": {"requests"}}, kept)
+}
+
+func TestGraph_CollectRemovable_Requested(t *testing.T) {
+	writeInstalled(t, map[string][]string{
+		"app":      {"requests"},
+		"requests": {"idna"},
+		"idna":     {},
+	})
+	// The dependency was installed on its own before, so it is kept along
+	// with its dependencies
+	writeTree(t, config.PythonLibPath, map[string]string{
+		"requests-1.0.dist-info/REQUESTED":         "",
+		"idna-1.0.dist-info/" + DependencyFileName: "",
+	})
+
+	g, err := LoadGraph()
+	assert.Nil(t, err)
+	removable, kept := g.CollectRemovable([]string{"app"})
+	assert.Equal(t, []string{"app"}, removable)
+	assert.Equal(t, map[string][]string{"idna": {"requests"}}, kept)
+}
+
+func TestGraph_CollectOrphans(t *testing.T) {
+	writeInstalled(t, map[string][]string{
+		"app":      {"requests"},
+		"idna":     {},
+		"requests": {"idna", "PySocks; extra == \"socks\""},
+		"pysocks":  {},
+		"left":     {"leftover"},
+		"leftover": {},
+		"system":   {},
+	})
+	files := map[string]string{}
+	for _, name := range []string{"requests", "idna", "pysocks", "left", "leftover"} {
+		files[name+"-1.0.dist-info/INSTALLER"] = "fext\n"
+		files[name+"-1.0.dist-info/"+DependencyFileName] = ""
+	}
+	files["app-1.0.dist-info/INSTALLER"] = "fext\n"
+	files["app-1.0.dist-info/REQUESTED"] = ""
+	writeTree(t, config.PythonLibPath, files)
+
+	// The extra dependency is kept, since the extra may have been requested
+	g, err := LoadGraph()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"left", "leftover"}, g.CollectOrphans())
+}
+
+func TestGraph_CollectOrphans_Legacy(t *testing.T) {
+	writeInstalled(t, map[string][]string{
+		"legacy": {"idna"},
+		"idna":   {},
+		"tool":   {},
+	})
+	// The packages installed by other tools, or by fext before the
+	// dependencies were marked, have no REQUESTED file
+	writeTree(t, config.PythonLibPath, map[string]string{
+		"legacy-1.0.dist-info/INSTALLER": "pip\n",
+		"idna-1.0.dist-info/INSTALLER":   "fext\n",
+		"tool-1.0.dist-info/INSTALLER":   "fext\n",
+	})
+
+	g, err := LoadGraph()
+	assert.Nil(t, err)
+	assert.Empty(t, g.CollectOrphans())
+
+	p := g.Get("idna")
+	assert.True(t, p.IsRequested())
+	writeTree(t, config.PythonLibPath, map[string]string{"idna-1.0.dist-info/" + DependencyFileName: ""})
+	assert.False(t, p.IsRequested())
+	assert.Nil(t, p.MarkRequested())
+	assert.True(t, p.IsRequested())
+	assert.NoFileExists(t, filepath.Join(config.PythonLibPath, "idna-1.0.dist-info", DependencyFileName))
+}
+
+func TestLoadGraph_Extras(t *testing.T) {
+	writeInstalled(t, map[string][]string{
+		"api":     {"uvicorn[standard]>=0.20"},
+		"uvicorn": {},
+	})
+	writeTree(t, config.PythonLibPath, map[string]string{"uvicorn-1.0.dist-info/" + DependencyFileName: ""})
+
+	g, err := LoadGraph()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api"}, g.GetDependents("uvicorn"))
+
+	removable, kept := g.CollectRemovable([]string{"uvicorn"})
+	assert.Equal(t, []string{"uvicorn"}, removable)
+	assert.Empty(t, kept)
+	removable, _ = g.CollectRemovable([]string{"api"})
+	assert.Equal(t, []string{"api", "uvicorn"}, removable)
+}
+
+func TestLoadGraph_Skipped(t *testing.T) {
+	writeInstalled(t, map[string][]string{
+		"app":     {"idna"},
+		"idna":    {},
+		"invalid": {"idna; os_name ==="},
+	})
+	writeTree(t, config.PythonLibPath, map[string]string{"broken-1.0.dist-info/RECORD": ""})
+
+	// The broken packages don't prevent the others from being loaded
+	g, err := LoadGraph()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app"}, g.GetDependents("idna"))
+	assert.NotNil(t, g.Get("invalid"))
+	assert.Len(t, g.GetSkipped(), 2)
+	assert.Contains(t, g.GetSkipped(), "broken-1.0.dist-info")
+	assert.Contains(t, g.GetSkipped(), "invalid-1.0.dist-info")
+}
@@ -0,0 +1,231 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+// RecordFileName is the name of the file in the meta-directory that lists all
+// installed files of the package.
+const RecordFileName = "RECORD"
+
+// ExecutablesFileName is the name of the file in the meta-directory that lists
+// the paths of the files installed with the executable bit, one per line, in
+// the same form as RECORD does.
+const ExecutablesFileName = "FEXT_EXECUTABLES"
+
+// RecordEntry is a line of the RECORD file
+// (https://packaging.python.org/en/latest/specifications/recording-installed-packages/#the-record-file).
+type RecordEntry struct {
+	// Path to the file relative to the directory with packages, with forward
+	// slashes. Files installed outside it, e.g. scripts, have paths starting
+	// with ".."
+	Path string
+	// Digest of the file content in the form "sha256=<urlsafe-base64>". It is
+	// empty for the RECORD file itself
+	Hash string
+	// File size in bytes. It is empty for the RECORD file itself
+	Size string
+}
+
+// NewRecordEntry computes the digest and the size of the file and returns the
+// entry describing it. The path is taken relative to the root directory.
+// Returns an error if the file can't be read.
+func NewRecordEntry(root, filePath string) (RecordEntry, error) {
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil {
+		return RecordEntry{}, err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return RecordEntry{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return RecordEntry{}, err
+	}
+
+	return RecordEntry{
+		Path: filepath.ToSlash(rel),
+		Hash: "sha256=" + base64.RawURLEncoding.EncodeToString(h.Sum(nil)),
+		Size: strconv.FormatInt(size, 10),
+	}, nil
+}
+
+// ScanRecord walks through the root directory and returns the entries for all
+// files in it, except the RECORD file of the meta-directory.
+// Returns an error if any file can't be read.
+func ScanRecord(root, metaDirName string) ([]RecordEntry, error) {
+	var entries []RecordEntry
+	recordPath := filepath.Join(root, metaDirName, RecordFileName)
+
+	err := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filePath == recordPath {
+			return err
+		}
+
+		entry, err := NewRecordEntry(root, filePath)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// WriteRecord writes the entries, sorted by path, to the RECORD file of the
+// meta-directory located in the root directory. The RECORD file itself is
+// listed without the digest and the size, as the specification requires.
+func WriteRecord(root, metaDirName string, entries []RecordEntry) error {
+	entries = append(entries, RecordEntry{Path: path.Join(filepath.ToSlash(metaDirName), RecordFileName)})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	f, err := os.Create(filepath.Join(root, metaDirName, RecordFileName))
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	for _, entry := range entries {
+		if err = w.Write([]string{entry.Path, entry.Hash, entry.Size}); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadRecord reads the entries from the RECORD file at the path.
+// Returns an error if the file is missing or malformed.
+func ReadRecord(recordPath string) ([]RecordEntry, error) {
+	f, err := os.Open(recordPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	// Some tools omit the trailing empty fields
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []RecordEntry
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+
+		entry := RecordEntry{Path: row[0]}
+		if len(row) > 1 {
+			entry.Hash = row[1]
+		}
+		if len(row) > 2 {
+			entry.Size = row[2]
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetRecordPath returns the absolute path to the RECORD file of the package.
+// It doesn't check if the file exists.
+func (p *Package) GetRecordPath() string {
+	return getAbsolutePath(p.metaDir, RecordFileName)
+}
+
+// resolveRecordPath returns the absolute path to the file listed in the
+// RECORD file. Relative paths are resolved against config.PythonLibPath.
+func resolveRecordPath(recordPath string) string {
+	fileName := filepath.FromSlash(recordPath)
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(config.PythonLibPath, fileName)
+	}
+
+	return fileName
+}
+
+// WriteExecutables writes the sorted paths of the executable files to the
+// "FEXT_EXECUTABLES" file of the meta-directory located in the root directory.
+// Returns the RECORD entry of the written file, or an error if it can't be
+// written.
+func WriteExecutables(root, metaDirName string, paths []string) (RecordEntry, error) {
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, p := range paths {
+		b.WriteString(p + "\n")
+	}
+
+	filePath := filepath.Join(root, metaDirName, ExecutablesFileName)
+	if err := os.WriteFile(filePath, []byte(b.String()), 0666); err != nil {
+		return RecordEntry{}, err
+	}
+
+	return NewRecordEntry(root, filePath)
+}
+
+// GetExecutableFiles returns the absolute paths to the package files that were
+// installed with the executable bit, according to the "FEXT_EXECUTABLES" file.
+// The packages installed by other tools have no such file, so only their
+// files located in config.ScriptsPath are taken for the executable ones.
+// Returns os.ErrNotExist if the package has neither of the files.
+func (p *Package) GetExecutableFiles() (map[string]bool, error) {
+	executables := map[string]bool{}
+
+	data, err := os.ReadFile(getAbsolutePath(p.metaDir, ExecutablesFileName))
+	if err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				executables[resolveRecordPath(line)] = true
+			}
+		}
+		return executables, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	files, err := p.GetRecordFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, fileName := range files {
+		if isInsideDirectory(config.ScriptsPath, fileName) {
+			executables[fileName] = true
+		}
+	}
+
+	return executables, nil
+}
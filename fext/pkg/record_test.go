@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fextpkg/cli/fext/config"
+)
+
+func TestRecord(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"pkg/__init__.py":             "",
+		"pkg/data, with comma.txt":    "data",
+		"pkg-1.0.dist-info/INSTALLER": "fext\n",
+		"pkg-1.0.dist-info/RECORD":    "stale",
+	})
+
+	entries, err := ScanRecord(root, "pkg-1.0.dist-info")
+	assert.Nil(t, err)
+	assert.Nil(t, WriteRecord(root, "pkg-1.0.dist-info", entries))
+
+	entries, err = ReadRecord(filepath.Join(root, "pkg-1.0.dist-info", RecordFileName))
+	assert.Nil(t, err)
+	assert.Equal(t, []RecordEntry{
+		{"pkg-1.0.dist-info/INSTALLER", "sha256=1q2hyq53wxH7TjY8nc5k7q8C5RRogJHfccx95c-UQe8", "5"},
+		{"pkg-1.0.dist-info/RECORD", "", ""},
+		{"pkg/__init__.py", "sha256=47DEQpj8HBSa-_TImW-5JCeuQeRkm5NMpJWZG3hSuFU", "0"},
+		{"pkg/data, with comma.txt", "sha256=Om6weQ85rIfJTzhWst0sXREOaBFgImGpqSPTuyOtyLc", "4"},
+	}, entries)
+}
+
+// writeTree creates the files with the content in the root directory.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestPackage_UninstallRecord(t *testing.T) {
+	libPath := config.PythonLibPath
+	t.Cleanup(func() { config.PythonLibPath = libPath })
+	config.PythonLibPath = t.TempDir()
+	outside := filepath.Join(filepath.Dir(config.PythonLibPath), "outside.txt")
+	assert.Nil(t, os.WriteFile(outside, nil, 0644))
+	t.Cleanup(func() { _ = os.Remove(outside) })
+
+	writeTree(t, config.PythonLibPath, map[string]string{
+		"ns/pkg/__init__.py":                          "",
+		"ns/pkg/__pycache__/__init__.cpython-311.pyc": "",
+		"ns/pkg/sub/module.py":                        "",
+		"ns/other/__init__.py":                        "",
+		"pkg.pth":                                     "",
+		"stray.py":                                    "",
+		"pkg-1.0.dist-info/METADATA":                  "Name: pkg\nVersion: 1.0\n",
+		"pkg-1.0.dist-info/top_level.txt":             "ns\nstray\n",
+		"pkg-1.0.dist-info/RECORD": "ns/pkg/__init__.py,,\n" +
+			"ns/pkg/sub/module.py,,\n" +
+			"pkg.pth,,\n" +
+			"../outside.txt,,\n" +
+			"pkg-1.0.dist-info/METADATA,,\n" +
+			"pkg-1.0.dist-info/RECORD,,\n",
+	})
+
+	p, err := LoadFromMetaDir("pkg-1.0.dist-info")
+	assert.Nil(t, err)
+	assert.Nil(t, p.Uninstall())
+
+	var left []string
+	err = filepath.Walk(config.PythonLibPath, func(path string, info os.FileInfo, err error) error {
+		rel, _ := filepath.Rel(config.PythonLibPath, path)
+		left = append(left, filepath.ToSlash(rel))
+		return err
+	})
+	assert.Nil(t, err)
+	// The namespace package shared with another distribution and the files
+	// that are not listed in the RECORD are kept
+	assert.Equal(t, []string{".", "ns", "ns/other", "ns/other/__init__.py", "stray.py"}, left)
+	// Paths outside the directory with packages are ignored
+	assert.FileExists(t, outside)
+}
+
+func TestPackage_GetRecordFiles_DataPath(t *testing.T) {
+	libPath, scriptsPath, dataPath := config.PythonLibPath, config.ScriptsPath, config.DataPath
+	t.Cleanup(func() {
+		config.PythonLibPath, config.ScriptsPath, config.DataPath = libPath, scriptsPath, dataPath
+	})
+	config.DataPath = t.TempDir()
+	config.PythonLibPath = filepath.Join(config.DataPath, "lib", "site-packages")
+	config.ScriptsPath = filepath.Join(config.DataPath, "bin")
+
+	writeTree(t, config.DataPath, map[string]string{
+		"share/pkg/data.txt":                           "",
+		"share/other.txt":                              "",
+		"lib/site-packages/pkg-1.0.dist-info/METADATA": "Name: pkg\nVersion: 1.0\n",
+		"lib/site-packages/pkg-1.0.dist-info/RECORD": "../../bin/tool,,\n" +
+			"../../share/pkg/data.txt,,\n" +
+			"../../share,,\n" +
+			"../..,,\n" +
+			"../../missing.txt,,\n",
+	})
+
+	p, err := LoadFromMetaDir("pkg-1.0.dist-info")
+	assert.Nil(t, err)
+	files, err := p.GetRecordFiles()
+	assert.Nil(t, err)
+	// The directories of the data path are never removed as a whole
+	assert.Equal(t, []string{
+		filepath.Join(config.ScriptsPath, "tool"),
+		filepath.Join(config.DataPath, "share", "pkg", "data.txt"),
+	}, files)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fextpkg/cli/fext/config"
@@ -41,6 +42,17 @@ type Dependency struct {
 	Conditions []expression.Condition
 }
 
+// GetMarkers returns the Python markers (PEP 508) under which the dependency
+// is required. Returns an empty string for the extra dependencies, since they
+// are required because of the extra names rather than the environment.
+func (d Dependency) GetMarkers() string {
+	if d.isExtra {
+		return ""
+	}
+
+	return d.markers
+}
+
 // Load a package by searching for its metadata directory and processing the
 // metadata. Returns an error if it fails to process the metadata or if the
 // directory is missing.
@@ -77,6 +89,19 @@ func LoadFromMetaDir(path string) (*Package, error) {
 	return &p, nil
 }
 
+// LoadFromMetadata parses the raw content of the METADATA file and returns a
+// Package that doesn't belong to any directory. It is used to inspect
+// packages that haven't been installed yet.
+func LoadFromMetadata(data []byte) *Package {
+	p := Package{
+		Dependencies: []Dependency{},
+		Extras:       []string{},
+	}
+	p.parseMetaDataContent(data)
+
+	return &p
+}
+
 // parseMetaData reads each line from the METADATA file in the specified
 // metaDir. Sets the obtained values to public attributes.
 // Returns an error if it fails to read the file.
@@ -86,6 +111,13 @@ func (p *Package) parseMetaData() error {
 		return err
 	}
 
+	p.parseMetaDataContent(data)
+	return nil
+}
+
+// parseMetaDataContent processes the content of the METADATA file and sets the
+// obtained values to public attributes.
+func (p *Package) parseMetaDataContent(data []byte) {
 	// Metadata file is always separated by "\n"
 	for _, s := range strings.Split(string(data), "\n") {
 		// Remove unnecessary escape sequence characters
@@ -115,7 +147,6 @@ func (p *Package) parseMetaData() error {
 			break
 		}
 	}
-	return nil
 }
 
 // getTopLevel scans the "top_level.txt" file, which contains the names of
@@ -164,38 +195,188 @@ func (p *Package) getDataDirectory() string {
 	return p.metaDir[:len(p.metaDir)-9] + "data"
 }
 
-// Uninstall deletes all directories and files belonging to this package
-func (p *Package) Uninstall() error {
+// GetFiles returns the absolute paths to all directories and files belonging
+// to this package. The RECORD file is used as the source of truth, and the
+// compiled bytecode of the listed modules is added to it. If the package has
+// no RECORD file, the files are guessed from the "top_level.txt". The
+// meta-directory is always included. Some of the paths may not exist, e.g.
+// the data directory.
+func (p *Package) GetFiles() ([]string, error) {
+	files, err := p.GetRecordFiles()
+	if errors.Is(err, os.ErrNotExist) {
+		return p.guessFiles()
+	} else if err != nil {
+		return nil, err
+	}
+
+	return append(files, p.GetMetaDirectoryPath()), nil
+}
+
+// GetRecordFiles returns the absolute paths to the files listed in the
+// RECORD file along with their compiled bytecode in "__pycache__". Paths
+// pointing outside the installation scheme paths are skipped, as well as the
+// directories in the data path, so a malformed RECORD can't affect the rest
+// of the system.
+// Returns os.ErrNotExist if the package has no RECORD file.
+func (p *Package) GetRecordFiles() ([]string, error) {
+	entries, err := ReadRecord(p.GetRecordPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		fileName := resolveRecordPath(entry.Path)
+		if !isInsideSchemePaths(fileName) && !isDataFile(fileName) {
+			continue
+		}
+		files = append(files, fileName)
+
+		if strings.HasSuffix(fileName, ".py") {
+			// Bytecode is compiled by the interpreter, so it is never listed
+			cache, err := filepath.Glob(filepath.Join(
+				filepath.Dir(fileName),
+				"__pycache__",
+				strings.TrimSuffix(filepath.Base(fileName), ".py")+".*.pyc",
+			))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, cache...)
+		}
+	}
+
+	return files, nil
+}
+
+// guessFiles returns the absolute paths to the top-level packages and modules
+// listed in the "top_level.txt", the meta-directory and the data directory.
+func (p *Package) guessFiles() ([]string, error) {
 	files, err := p.getSourceFiles()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	files = append(files, p.metaDir, p.getDataDirectory())
+	for i, fileName := range files {
+		files[i] = getAbsolutePath(fileName)
+	}
+
+	return files, nil
+}
+
+// Uninstall deletes all directories and files belonging to this package, then
+// removes the directories that become empty.
+func (p *Package) Uninstall() error {
+	files, err := p.GetFiles()
+	if err != nil {
+		return err
+	}
+
 	for _, fileName := range files {
-		if err = os.RemoveAll(getAbsolutePath(fileName)); err != nil {
+		if err = os.RemoveAll(fileName); err != nil {
 			// Important: RemoveAll doesn't return an error if the file
 			// not exists
 			return err
 		}
 	}
 
+	for _, dir := range GetParentDirectories(files) {
+		if empty, err := isEmptyDirectory(dir); err != nil {
+			return err
+		} else if empty {
+			if err = os.Remove(dir); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+// GetParentDirectories returns the parent directories of the files located
+// inside config.PythonLibPath, excluding config.PythonLibPath itself. Nested
+// directories come before their parents, so they can be pruned in order.
+func GetParentDirectories(files []string) []string {
+	var dirs []string
+	seen := map[string]bool{}
+
+	for _, fileName := range files {
+		for dir := filepath.Dir(fileName); isInsideLibPath(dir) && !seen[dir]; dir = filepath.Dir(dir) {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+
+	return dirs
+}
+
+// isEmptyDirectory checks if the directory exists and has no entries.
+func isEmptyDirectory(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return len(entries) == 0, nil
+}
+
+// isInsideLibPath checks if the path is located inside config.PythonLibPath
+// and is not the directory itself.
+func isInsideLibPath(path string) bool {
+	return isInsideDirectory(config.PythonLibPath, path)
+}
+
+// isInsideSchemePaths checks if the path is located inside any of the
+// directories the package files are installed to, except the data path.
+func isInsideSchemePaths(path string) bool {
+	for _, dir := range []string{config.PythonLibPath, config.ScriptsPath, config.HeadersPath} {
+		if isInsideDirectory(dir, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDataFile checks if the path is an existing file installed from the "data"
+// subdirectory of the wheel data directory. The data path is the root of the
+// environment or "~/.local", so only the files are accepted there, never the
+// directories.
+func isDataFile(path string) bool {
+	if !isInsideDirectory(config.DataPath, path) {
+		return false
+	}
+
+	info, err := os.Lstat(path)
+	return err == nil && !info.IsDir()
+}
+
+// isInsideDirectory checks if the path is located inside the directory and is
+// not the directory itself.
+func isInsideDirectory(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
 // GetSize calculates the total size of all files belonging to the package and
 // returns the size in bytes.
 func (p *Package) GetSize() (int64, error) {
-	files, err := p.getSourceFiles()
+	files, err := p.GetFiles()
 	if err != nil {
 		return 0, err
 	}
 
-	files = append(files, p.metaDir, p.getDataDirectory())
 	var size int64
 	for _, fileName := range files {
 		_ = filepath.Walk(
-			getAbsolutePath(fileName), func(_ string, info os.FileInfo, _ error) error {
+			fileName, func(_ string, info os.FileInfo, _ error) error {
 				// Ignore the error as the data directory may not exist
 				if info != nil && !info.IsDir() {
 					// The weight of folders is always incorrect,
@@ -210,6 +391,47 @@ func (p *Package) GetSize() (int64, error) {
 	return size, nil
 }
 
+// RequestedFileName is the name of the file in the meta-directory that marks
+// the package requested by the user (PEP 376).
+const RequestedFileName = "REQUESTED"
+
+// DependencyFileName is the name of the file in the meta-directory that marks
+// the package installed by fext as a dependency of another package.
+const DependencyFileName = "FEXT_DEPENDENCY"
+
+// IsRequested checks if the package was requested by the user rather than
+// installed as a dependency of another package. The package is considered
+// requested unless it is marked with the "FEXT_DEPENDENCY" file, since the
+// packages installed by other tools or older versions of fext may lack the
+// "REQUESTED" file (PEP 376) even if they were requested.
+func (p *Package) IsRequested() bool {
+	if _, err := os.Stat(getAbsolutePath(p.metaDir, RequestedFileName)); err == nil {
+		return true
+	}
+
+	_, err := os.Stat(getAbsolutePath(p.metaDir, DependencyFileName))
+	return err != nil
+}
+
+// MarkRequested marks the package installed as a dependency as requested by
+// the user, so it is never taken for an orphan.
+// Returns an error if the meta-directory can't be changed.
+func (p *Package) MarkRequested() error {
+	f, err := os.Create(getAbsolutePath(p.metaDir, RequestedFileName))
+	if err != nil {
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+
+	err = os.Remove(getAbsolutePath(p.metaDir, DependencyFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
 // GetMetaDirectoryPath returns the absolute path to the package's meta-directory.
 func (p *Package) GetMetaDirectoryPath() string {
 	return getAbsolutePath(p.metaDir)
@@ -274,6 +496,37 @@ func (p *Package) GetExtraDependencies(extraName string) ([]Dependency, error) {
 	return extraPackages, nil
 }
 
+// GetDependencyNames returns the normalized names of the package
+// dependencies required in the current environment, including the
+// dependencies of all its extras.
+// Returns an error if the dependencies can't be parsed.
+func (p *Package) GetDependencyNames() ([]string, error) {
+	dependencies, err := p.GetDependencies()
+	if err != nil {
+		return nil, err
+	}
+	for _, extraName := range p.Extras {
+		extraDependencies, err := p.GetExtraDependencies(extraName)
+		if err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, extraDependencies...)
+	}
+
+	names := make([]string, 0, len(dependencies))
+	for _, dep := range dependencies {
+		// The dependency name may be followed by its extra names, e.g.
+		// "uvicorn[standard]"
+		name, _, err := expression.ParseExtraNames(dep.PackageName)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, expression.NormalizeName(name))
+	}
+
+	return names, nil
+}
+
 // HasExtraName checks if the extra dependency name exists.
 func (p *Package) HasExtraName(name string) bool {
 	for _, depName := range p.Extras {
@@ -330,11 +583,11 @@ func getPackageMetaDir(pkgName string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	pkgName = formatName(pkgName)
+	pkgName = expression.NormalizeName(pkgName)
 
 	for _, dir := range dirInfo {
 		curPkgName, _, ext := parseDirectoryName(dir.Name())
-		if formatName(curPkgName) == pkgName && ext == "dist-info" {
+		if expression.NormalizeName(curPkgName) == pkgName && ext == "dist-info" {
 			return dir.Name(), nil
 		}
 	}
@@ -10,8 +10,11 @@ func PrintHelp() {
 		"\n\nAvailable commands:\n",
 		"\t(i)nstall <package(s)>   - install a package(s)\n",
 		"\t(u)ninstall <package(s)> - uninstall a package(s)\n",
+		"\tautoremove               - uninstall dependencies that no requested package needs\n",
+		"\t(l)ock <package(s)>      - write the exact versions of a package(s) to the lock file\n",
 		"\t(f)reeze                 - show list of installed packages\n",
 		"\tshow <package>           - show general info about package\n",
+		"\toutdated                 - show installed packages that have newer versions\n",
 		"\tcheck                    - verify correct installation of packages in the system\n",
 		"\tdebug                    - show debug info",
 		"\n\nFor additional help you can write:\n\tfext <command> -h")
@@ -22,18 +25,57 @@ func PrintHelpInstall() {
 	fmt.Println("Available options:\n",
 		"\t-n, --no-dependencies      - Install single package, without dependencies\n",
 		"\t-q, --quiet (-s, --silent) - Print only error messages\n",
-		"\t-r, --requirements         - Install from files",
+		"\t-r, --requirements         - Install from files\n",
+		"\t--require-hashes           - Install only pinned packages with known hashes\n",
+		"\t--locked                   - Install exactly the packages from lock files\n",
+		"\t-f, --find-links=<dir>     - Look for wheels in the local directory also\n",
+		"\t--offline                  - Do not access the network, use local wheels only\n",
+		"\t-i, --index-url=<url>      - Set the package index URL: https://pypi.org/simple/ (default)\n",
+		"\t--extra-index-url=<url>    - Look for packages in the additional index also",
+	)
+}
+
+// PrintHelpLock prints lock help info
+func PrintHelpLock() {
+	fmt.Println("The lock file is valid for the current interpreter and platform only.",
+		"\n\nAvailable options:\n",
+		"\t-r, --requirements      - Lock packages from files\n",
+		"\t-o, --output=<str>      - set the lock file path: fext.lock (default)\n",
+		"\t-f, --find-links=<dir>  - Look for wheels in the local directory also\n",
+		"\t--offline               - Do not access the network, use local wheels only\n",
+		"\t-i, --index-url=<url>   - Set the package index URL: https://pypi.org/simple/ (default)\n",
+		"\t--extra-index-url=<url> - Look for packages in the additional index also",
 	)
 }
 
 func PrintHelpUninstall() {
 	fmt.Println("Available options:\n",
-		"\t-d, --dependencies - Remove dependencies of package also")
+		"\t-d, --dependencies - Remove dependencies of package also, unless other packages need them")
+}
+
+func PrintHelpAutoremove() {
+	fmt.Println("Available options:\n",
+		"\t-n, --dry-run - Show packages to be removed without removing them\n",
+		"\t-y, --yes     - Remove packages without asking for confirmation")
 }
 
 func PrintHelpFreeze() {
 	fmt.Println("Available options:\n",
-		"\t-m, --mode=<str> - set the print mode: human (default), pip")
+		"\t-m, --mode=<str>        - set the print mode: human (default), pip, pylock\n",
+		"\t--offline               - Do not access the network, do not pin wheels (pylock)\n",
+		"\t-i, --index-url=<url>   - Set the package index URL (pylock): https://pypi.org/simple/ (default)\n",
+		"\t--extra-index-url=<url> - Look for wheels in the additional index also (pylock)")
+}
+
+func PrintHelpShow() {
+	fmt.Println("Available options:\n",
+		"\t-y, --yanked  - Check if the installed version has been yanked from the index\n",
+		"\t-v, --verbose - Print the errors of the index lookup")
+}
+
+func PrintHelpOutdated() {
+	fmt.Println("Available options:\n",
+		"\t-v, --verbose - Print the errors of the index lookup")
 }
 
 func PrintUnknownOption(opt string) {
@@ -0,0 +1,24 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Confirm prints the question and reads the answer from stdin. Only "y" and
+// "yes" are taken for consent, any other answer or the closed stdin are taken
+// for refusal.
+func Confirm(question string) bool {
+	fmt.Print(question + " [y/N] ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}